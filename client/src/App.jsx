@@ -1,13 +1,15 @@
 // MainApp.jsx
 import React, { useState, useMemo } from "react";
 import { useInvestData } from "./hooks/useInvestData";
-import { generateInvestorPdfBlob } from "./utils/investorPdfReport";
 
 import {
   createTopup,
+  fetchInvestorStatement,
   fetchPayouts,
+  newIdempotencyKey,
   createTakeProfit,
   createCapitalWithdraw,
+  saveInvestorRate,
 } from "./api/api";
 
 import InvestorsTable from "./components/InvestorsTable";
@@ -112,6 +114,7 @@ export default function MainApp({ logout }) {
       monthKey: currentMonthKey,
       amount: "",
       isSaving: false,
+      idempotencyKey: newIdempotencyKey(),
     });
 
   const closeTopupModal = () =>
@@ -135,7 +138,7 @@ export default function MainApp({ logout }) {
     setTopupModal((p) => ({ ...p, isSaving: true }));
 
     try {
-      await createTopup(inv.id, topupModal.monthKey, amount);
+      await createTopup(inv.id, topupModal.monthKey, amount, topupModal.idempotencyKey);
 
       const fresh = await fetchPayouts();
       setPayouts(
@@ -160,18 +163,14 @@ export default function MainApp({ logout }) {
   async function handleShareReport(inv) {
     if (!inv) return;
 
-    const pdfBlob = await generateInvestorPdfBlob({
-      investor: inv,
-      payouts,
-      getCapitalNow,
-      getCurrentNetProfit,
-      getTotalProfitAllTime,
-      withdrawnTotal: getWithdrawnCapitalTotal,
-      getTopupsTotal: (id) =>
-        payouts
-          .filter((p) => p.investorId === id && p.isTopup)
-          .reduce((sum, p) => sum + (p.payoutAmount || 0), 0),
-    });
+    let pdfBlob;
+    try {
+      pdfBlob = await fetchInvestorStatement(inv.id);
+    } catch (err) {
+      console.error("Ошибка выписки:", err);
+      alert(err.message);
+      return;
+    }
 
     const file = new File(
       [pdfBlob],
@@ -258,25 +257,24 @@ export default function MainApp({ logout }) {
       monthKey: currentMonthKey,
       reinvest: true,
       isSaving: false,
+      idempotencyKey: newIdempotencyKey(),
     });
 
   async function confirmPayout() {
-    const { investor, reinvest, monthKey } = payoutModal;
+    const { investor, reinvest, monthKey, idempotencyKey } = payoutModal;
     const percent = percents[investor.id] || 0;
     const capital = getCapitalNow(investor);
     const amount = Math.round((capital * percent) / 100);
 
+    // ставка сохраняется на сервере и не сбрасывается после выплаты
+    await saveInvestorRate(investor.id, percent);
+
     await savePayout({
       investorId: investor.id,
       month: monthKey,
       amount,
       reinvest,
-    });
-
-    setPercents((prev) => {
-      const out = { ...prev };
-      delete out[investor.id];
-      return out;
+      idempotencyKey,
     });
 
     setPayoutModal({ open: false, investor: null });
@@ -290,6 +288,7 @@ export default function MainApp({ logout }) {
       monthKey: currentMonthKey,
       amount: "",
       isSaving: false,
+      idempotencyKey: newIdempotencyKey(),
     });
 
   async function confirmWithdraw() {
@@ -304,13 +303,16 @@ export default function MainApp({ logout }) {
     const profitPart = Math.min(amount, net);
     const capitalPart = amount - profitPart;
 
+    // снятие может состоять из двух операций — у каждой свой ключ
+    const key = withdrawModal.idempotencyKey;
+
     try {
       if (profitPart > 0) {
-        await createTakeProfit(inv.id, withdrawModal.monthKey, profitPart);
+        await createTakeProfit(inv.id, withdrawModal.monthKey, profitPart, `${key}:profit`);
       }
 
       if (capitalPart > 0) {
-        await createCapitalWithdraw(inv.id, withdrawModal.monthKey, capitalPart);
+        await createCapitalWithdraw(inv.id, withdrawModal.monthKey, capitalPart, `${key}:capital`);
       }
 
       const fresh = await fetchPayouts();
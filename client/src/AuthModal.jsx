@@ -1,11 +1,27 @@
 import { useState } from "react";
-import { registerUser, loginUser } from "./api/api";
+import {
+  registerUser,
+  loginUser,
+  loginSecondFactor,
+  requestPasswordReset,
+  resetPassword,
+} from "./api/api";
+
+// ссылка из письма: /reset-password?token=...
+function resetTokenFromURL() {
+  if (window.location.pathname !== "/reset-password") return null;
+  return new URLSearchParams(window.location.search).get("token");
+}
 
 export default function AuthModal({ onAuthenticated }) {
-  const [mode, setMode] = useState("login");
+  const [resetToken] = useState(resetTokenFromURL);
+  const [mode, setMode] = useState(resetToken ? "reset" : "login");
+  const [info, setInfo] = useState("");
   const [email, setEmail] = useState("");
   const [password, setPassword] = useState("");
   const [secret, setSecret] = useState("");
+  const [mfaToken, setMfaToken] = useState(null);
+  const [code, setCode] = useState("");
   const [error, setError] = useState("");
   const [loading, setLoading] = useState(false);
 
@@ -15,15 +31,28 @@ export default function AuthModal({ onAuthenticated }) {
 
     try {
       let data;
-      if (mode === "register") {
+      if (mode === "reset") {
+        await resetPassword(resetToken, password);
+        window.history.replaceState(null, "", "/");
+        setPassword("");
+        setMode("login");
+        setInfo("Пароль изменён, войдите с новым паролем");
+        return;
+      } else if (mfaToken) {
+        data = await loginSecondFactor(mfaToken, code);
+      } else if (mode === "register") {
         if (!secret.trim()) {
-          setError("Введите секретный код");
+          setError("Введите код приглашения");
           setLoading(false);
           return;
         }
         data = await registerUser(email, password, secret);
       } else {
         data = await loginUser(email, password);
+        if (data.mfaRequired) {
+          setMfaToken(data.mfaToken);
+          return;
+        }
       }
 
       onAuthenticated(data.token);
@@ -44,9 +73,19 @@ export default function AuthModal({ onAuthenticated }) {
         "
       >
         <h2 className="text-3xl text-white font-bold text-center tracking-wide drop-shadow-md">
-          {mode === "login" ? "Добро пожаловать" : "Создать аккаунт"}
+          {mode === "login"
+            ? "Добро пожаловать"
+            : mode === "reset"
+              ? "Новый пароль"
+              : "Создать аккаунт"}
         </h2>
 
+        {info && (
+          <div className="text-emerald-400 text-center text-sm bg-emerald-400/10 py-2 rounded-lg border border-emerald-400/30">
+            {info}
+          </div>
+        )}
+
         {error && (
           <div className="text-red-400 text-center text-sm bg-red-400/10 py-2 rounded-lg border border-red-400/30">
             {error}
@@ -54,6 +93,7 @@ export default function AuthModal({ onAuthenticated }) {
         )}
 
         <div className="space-y-4">
+          {mode !== "reset" && (
           <input
             type="email"
             placeholder="Ваш Email"
@@ -66,10 +106,11 @@ export default function AuthModal({ onAuthenticated }) {
               transition-all
             "
           />
+          )}
 
           <input
             type="password"
-            placeholder="Ваш пароль"
+            placeholder={mode === "reset" ? "Новый пароль" : "Ваш пароль"}
             value={password}
             onChange={(e) => setPassword(e.target.value)}
             className="
@@ -80,10 +121,27 @@ export default function AuthModal({ onAuthenticated }) {
             "
           />
 
+          {mfaToken && (
+            <input
+              type="text"
+              inputMode="numeric"
+              autoComplete="one-time-code"
+              placeholder="Код из приложения"
+              value={code}
+              onChange={(e) => setCode(e.target.value)}
+              className="
+                w-full p-3 rounded-xl bg-slate-800/60 border border-blue-600 
+                text-white placeholder-slate-400
+                focus:ring-2 focus:ring-blue-400 focus:border-blue-400 outline-none
+                transition-all
+              "
+            />
+          )}
+
           {mode === "register" && (
             <input
               type="password"
-              placeholder="Секретный код"
+              placeholder="Код приглашения"
               value={secret}
               onChange={(e) => setSecret(e.target.value)}
               className="
@@ -108,10 +166,33 @@ export default function AuthModal({ onAuthenticated }) {
           `}
         >
           {loading
-            ? (mode === "login" ? "Входим..." : "Создаём...")
-            : (mode === "login" ? "Войти" : "Создать аккаунт")}
+            ? (mode === "login" ? "Входим..." : mode === "reset" ? "Сохраняем..." : "Создаём...")
+            : (mode === "login" ? "Войти" : mode === "reset" ? "Сохранить пароль" : "Создать аккаунт")}
         </button>
 
+        {mode === "login" && !mfaToken && (
+          <div className="text-sm text-center">
+            <button
+              className="text-slate-400 hover:text-slate-200 underline transition"
+              onClick={async () => {
+                setError("");
+                if (!email.trim()) {
+                  setError("Введите email, чтобы получить ссылку для сброса");
+                  return;
+                }
+                try {
+                  await requestPasswordReset(email);
+                  setInfo("Если такой аккаунт есть, мы отправили ссылку для сброса пароля");
+                } catch (err) {
+                  setError(err.message || "Ошибка");
+                }
+              }}
+            >
+              Забыли пароль?
+            </button>
+          </div>
+        )}
+
         <div className="text-sm text-center text-slate-300">
           {mode === "login" ? (
             <>
@@ -1,6 +1,10 @@
-import { useState } from "react";
+import { useState, useEffect } from "react";
 import App from "./App";
 import AuthModal from "./AuthModal";
+import { refreshSession, logoutUser } from "./api/api";
+
+// access-токен живёт 15 минут — обновляем заранее
+const REFRESH_INTERVAL_MS = 10 * 60 * 1000;
 
 export default function RootApp() {
   const [token, setToken] = useState(localStorage.getItem("token"));
@@ -10,16 +14,34 @@ export default function RootApp() {
     setToken(jwt);
   };
 
-  const logout = () => {
-    localStorage.removeItem("token");
+  const logout = async () => {
+    await logoutUser().catch(() => {});
     setToken(null);
   };
 
+  useEffect(() => {
+    if (!token) return;
+
+    const refresh = () =>
+      refreshSession()
+        .then((data) => setToken(data.token))
+        .catch(() => {
+          localStorage.removeItem("token");
+          localStorage.removeItem("refreshToken");
+          setToken(null);
+        });
+
+    // сразу при загрузке: сохранённый токен мог уже истечь
+    refresh();
+    const id = setInterval(refresh, REFRESH_INTERVAL_MS);
+    return () => clearInterval(id);
+  }, [token !== null]);
+
   return (
     <>
 
     {!token && <AuthModal onAuthenticated={handleAuthenticated} />}
-    {token && <App />}
+    {token && <App logout={logout} />}
   </>
 
   );
@@ -13,19 +13,40 @@ function authHeaders() {
   };
 }
 
+// Idempotency-Key: повтор запроса с тем же ключом (двойной клик, ретрай
+// на мобильной сети) сервер не проводит второй раз, а отдаёт прежний ответ.
+// Ключ создаётся один раз на действие пользователя (открытие модалки).
+export function newIdempotencyKey() {
+  if (crypto.randomUUID) return crypto.randomUUID();
+  return `${Date.now()}-${Math.random().toString(36).slice(2)}`;
+}
+
+function moneyHeaders(idempotencyKey) {
+  return {
+    ...authHeaders(),
+    ...(idempotencyKey ? { "Idempotency-Key": idempotencyKey } : {}),
+  };
+}
+
 // ============ AUTH ============
 
-export async function registerUser(email, password, secretCode) {
+function saveSession(data) {
+  localStorage.setItem("token", data.token);
+  if (data.refreshToken) localStorage.setItem("refreshToken", data.refreshToken);
+}
+
+// code — токен приглашения; старый общий код тоже принимается, если включён на сервере
+export async function registerUser(email, password, code) {
   const res = await fetch(`${API_URL}/register`, {
     method: "POST",
     headers: { "Content-Type": "application/json" },
-    body: JSON.stringify({ email, password, secretCode }),
+    body: JSON.stringify({ email, password, inviteToken: code, secretCode: code }),
   });
 
   const data = await res.json();
   if (!res.ok) throw new Error(data.error || "Registration failed");
 
-  localStorage.setItem("token", data.token);
+  saveSession(data);
   return data;
 }
 
@@ -39,10 +60,99 @@ export async function loginUser(email, password) {
   const data = await res.json();
   if (!res.ok) throw new Error(data.error || "Login failed");
 
-  localStorage.setItem("token", data.token);
+  // включена 2FA — токен выдаст loginSecondFactor
+  if (data.mfaRequired) return data;
+
+  saveSession(data);
   return data;
 }
 
+// второй шаг входа: код из приложения-аутентификатора или код восстановления
+export async function loginSecondFactor(mfaToken, code) {
+  const res = await fetch(`${API_URL}/login/2fa`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ mfaToken, code }),
+  });
+
+  const data = await res.json();
+  if (!res.ok) throw new Error(data.error || "Invalid code");
+
+  saveSession(data);
+  return data;
+}
+
+// access-токен живёт 15 минут — продлеваем его одноразовым refresh-токеном
+export async function refreshSession() {
+  const refreshToken = localStorage.getItem("refreshToken");
+  if (!refreshToken) throw new Error("No refresh token");
+
+  const res = await fetch(`${API_URL}/refresh`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ refreshToken }),
+  });
+
+  const data = await res.json();
+  if (!res.ok) throw new Error(data.error || "Refresh failed");
+
+  saveSession(data);
+  return data;
+}
+
+export async function changePassword(currentPassword, newPassword) {
+  const res = await fetch(`${API_URL}/me/password`, {
+    method: "POST",
+    headers: authHeaders(),
+    body: JSON.stringify({ currentPassword, newPassword }),
+  });
+
+  if (!res.ok) {
+    const data = await res.json().catch(() => ({}));
+    throw new Error(data.error || "Failed to change password");
+  }
+}
+
+// письмо со ссылкой сброса; сервер не сообщает, существует ли такой email
+export async function requestPasswordReset(email) {
+  const res = await fetch(`${API_URL}/password/forgot`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ email }),
+  });
+
+  if (!res.ok) {
+    const data = await res.json().catch(() => ({}));
+    throw new Error(data.error || "Failed to request reset");
+  }
+}
+
+export async function resetPassword(token, newPassword) {
+  const res = await fetch(`${API_URL}/password/reset`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ token, newPassword }),
+  });
+
+  if (!res.ok) {
+    const data = await res.json().catch(() => ({}));
+    throw new Error(data.error || "Failed to reset password");
+  }
+}
+
+// allSessions — выйти на всех устройствах
+export async function logoutUser(allSessions = false) {
+  try {
+    await fetch(`${API_URL}/${allSessions ? "logout-all" : "logout"}`, {
+      method: "POST",
+      headers: authHeaders(),
+    });
+  } finally {
+    localStorage.removeItem("token");
+    localStorage.removeItem("refreshToken");
+  }
+}
+
 // ============ INVESTORS ============
 
 export async function fetchInvestors() {
@@ -57,10 +167,28 @@ export async function fetchInvestors() {
     id: i.id,
     fullName: i.full_name,
     investedAmount: Number(i.invested_amount),
+    monthlyPercent: i.monthly_percent ?? null,
     createdAt: i.created_at,
+    version: i.version,
   }));
 }
 
+// месячный процент хранится на сервере (действует с effectiveFrom, по умолчанию — с сегодня)
+export async function saveInvestorRate(investorId, monthlyPercent, effectiveFrom) {
+  const res = await fetch(`${API_URL}/investors/${investorId}/rate`, {
+    method: "PUT",
+    headers: authHeaders(),
+    body: JSON.stringify({
+      monthly_percent: String(monthlyPercent),
+      ...(effectiveFrom ? { effective_from: effectiveFrom } : {}),
+    }),
+  });
+
+  const data = await res.json();
+  if (!res.ok) throw new Error(data.error || "Failed to save rate");
+  return data;
+}
+
 export async function createInvestor(fullName, investedAmount) {
   const res = await fetch(`${API_URL}/investors`, {
     method: "POST",
@@ -85,7 +213,7 @@ export async function createInvestor(fullName, investedAmount) {
 // ============ PAYOUTS ============
 
 // единая нормализация payout, чтобы везде структура была одинаковой
-function normalizePayout(p) {
+export function normalizePayout(p) {
   if (!p) return null;
 
   // backend может прислать period_date или period_month
@@ -101,38 +229,106 @@ function normalizePayout(p) {
     periodMonth,
     payoutAmount: Number(p.payout_amount),
 
-    reinvest: !!p.reinvest,
-    isWithdrawalProfit: !!p.is_withdrawal_profit,
-    isWithdrawalCapital: !!p.is_withdrawal_capital,
-    isTopup: !!p.is_topup,
+    // kind — основной тип операции; флаги оставлены для старого кода
+    kind: p.kind,
+    reinvest: p.kind === "reinvest",
+    isWithdrawalProfit: p.kind === "profit_withdrawal",
+    isWithdrawalCapital: p.kind === "capital_withdrawal",
+    isTopup: p.kind === "topup",
+
+    // изменение вложенной суммы — уже учтено в investedAmount инвестора
+    isInvestment: p.kind === "deposit" || p.kind === "adjustment",
+
+    // сторно: отменённая запись и компенсирующая к ней взаимно погашаются
+    reversed: !!p.reversed,
+    reversesId: p.reverses_id ?? null,
 
     createdAt: p.created_at,
   };
 }
 
-export async function fetchPayouts() {
-  const res = await fetch(`${API_URL}/payouts`, { headers: authHeaders() });
+// все операции: сервер отдаёт их страницами, следующая — по X-Next-Cursor.
+// params — фильтры GET /api/payouts (investor_id, from, to, kind, sort, ...)
+export async function fetchPayouts(params = {}) {
+  const all = [];
+  let cursor = null;
 
-  if (!res.ok) return [];
+  do {
+    const query = new URLSearchParams({
+      limit: "1000",
+      ...params,
+      ...(cursor ? { cursor } : {}),
+    });
 
-  const data = await res.json();
-  const safe = Array.isArray(data) ? data : [];
+    const res = await fetch(`${API_URL}/payouts?${query}`, { headers: authHeaders() });
+    if (!res.ok) return [];
+
+    const data = await res.json();
+    if (Array.isArray(data)) all.push(...data);
 
-  return safe.map(normalizePayout);
+    cursor = res.headers.get("X-Next-Cursor");
+  } while (cursor);
+
+  // отменённые и компенсирующие записи в расчётах не участвуют
+  return all.map(normalizePayout).filter((p) => !p.reversed && !p.reversesId);
+}
+
+// ============ REPORTS ============
+
+// месяц × инвестор, посчитанный на сервере: операции, суммы по типам и капитал.
+// from / to — YYYY-MM; без них — от первой операции до текущего месяца
+export async function fetchMonthlyGrid(from, to) {
+  const query = new URLSearchParams({
+    ...(from ? { from } : {}),
+    ...(to ? { to } : {}),
+  });
+
+  const res = await fetch(`${API_URL}/reports/monthly-grid?${query}`, {
+    headers: authHeaders(),
+  });
+  if (!res.ok) return null;
+
+  const grid = await res.json();
+  return {
+    ...grid,
+    months: (grid.months || []).map((m) => ({
+      ...m,
+      investors: (m.investors || []).map((c) => ({
+        ...c,
+        operations: (c.operations || []).map(normalizePayout),
+      })),
+    })),
+  };
+}
+
+// PDF-выписка инвестора, собранная на сервере; from / to — YYYY-MM-DD,
+// без них — от первой операции до сегодня
+export async function fetchInvestorStatement(investorId, from, to) {
+  const query = new URLSearchParams({
+    ...(from ? { from } : {}),
+    ...(to ? { to } : {}),
+  });
+
+  const res = await fetch(`${API_URL}/investors/${investorId}/statement.pdf?${query}`, {
+    headers: authHeaders(),
+  });
+  if (!res.ok) {
+    const data = await res.json().catch(() => ({}));
+    throw new Error(data.error || "Не удалось получить выписку");
+  }
+  return res.blob();
 }
 
 // === Реинвест ===
-export async function createReinvest(investorId, date, amount) {
+export async function createReinvest(investorId, date, amount, idempotencyKey) {
   const res = await fetch(`${API_URL}/payouts`, {
     method: "POST",
-    headers: authHeaders(),
+    headers: moneyHeaders(idempotencyKey),
     body: JSON.stringify({
       investorId,
       date,                 // YYYY-MM-DD
       payoutAmount: amount,
-      reinvest: true,
-      isWithdrawalProfit: false,
-      isWithdrawalCapital: false,
+      kind: "reinvest",
     }),
   });
 
@@ -142,17 +338,15 @@ export async function createReinvest(investorId, date, amount) {
 }
 
 // === Снятие прибыли ===
-export async function createTakeProfit(investorId, date, amount) {
+export async function createTakeProfit(investorId, date, amount, idempotencyKey) {
   const res = await fetch(`${API_URL}/payouts`, {
     method: "POST",
-    headers: authHeaders(),
+    headers: moneyHeaders(idempotencyKey),
     body: JSON.stringify({
       investorId,
       date,
       payoutAmount: amount,
-      reinvest: false,
-      isWithdrawalProfit: true,
-      isWithdrawalCapital: false,
+      kind: "profit_withdrawal",
     }),
   });
 
@@ -162,10 +356,10 @@ export async function createTakeProfit(investorId, date, amount) {
 }
 
 // === Пополнение капитала ===
-export async function createTopup(investorId, date, amount) {
+export async function createTopup(investorId, date, amount, idempotencyKey) {
   const res = await fetch(`${API_URL}/payouts/topup`, {
     method: "POST",
-    headers: authHeaders(),
+    headers: moneyHeaders(idempotencyKey),
     body: JSON.stringify({
       investorId,
       date,
@@ -179,17 +373,15 @@ export async function createTopup(investorId, date, amount) {
 }
 
 // === Снятие капитала ===
-export async function createCapitalWithdraw(investorId, date, amount) {
+export async function createCapitalWithdraw(investorId, date, amount, idempotencyKey) {
   const res = await fetch(`${API_URL}/payouts`, {
     method: "POST",
-    headers: authHeaders(),
+    headers: moneyHeaders(idempotencyKey),
     body: JSON.stringify({
       investorId,
       date,
       payoutAmount: -Math.abs(amount), // отрицательное значение
-      reinvest: false,
-      isWithdrawalProfit: false,
-      isWithdrawalCapital: true,
+      kind: "capital_withdrawal",
     }),
   });
 
@@ -1,5 +1,7 @@
 import ExcelJS from "exceljs";
 import { saveAs } from "file-saver";
+import { fetchMonthlyGrid } from "../api/api";
+import { gridToSlots } from "../hooks/useMonthSlots";
 
 export default function ExcelExporter({
   investors,
@@ -17,47 +19,6 @@ export default function ExcelExporter({
     return null;
   }
 
-  // =========================================
-  // Группируем payout по реальному месяцу
-  // =========================================
-  function buildMonthSlots() {
-    const byMonthInv = new Map();
-
-    payouts.forEach((p) => {
-      const realDate = getRealDate(p);
-      if (!realDate) return;
-
-      const month = realDate.slice(0, 7); // YYYY-MM
-
-      if (!byMonthInv.has(month)) {
-        byMonthInv.set(month, new Map());
-      }
-
-      const invMap = byMonthInv.get(month);
-      const list = invMap.get(p.investorId) || [];
-      list.push(p);
-      invMap.set(p.investorId, list);
-    });
-
-    const slots = [];
-    const months = Array.from(byMonthInv.keys()).sort();
-
-    months.forEach((month) => {
-      const invMap = byMonthInv.get(month);
-      let maxLen = 0;
-
-      for (const list of invMap.values()) {
-        if (list.length > maxLen) maxLen = list.length;
-      }
-
-      for (let i = 0; i < maxLen; i++) {
-        slots.push({ month, index: i });
-      }
-    });
-
-    return { slots, byMonthInv };
-  }
-
   const exportToExcel = async () => {
     const workbook = new ExcelJS.Workbook();
     const sheet = workbook.addWorksheet("Инвесторы");
@@ -85,7 +46,10 @@ export default function ExcelExporter({
     };
 
     // ============ Динамика месяцев ============
-    const { slots, byMonthInv } = buildMonthSlots();
+    // та же сетка, что в таблице, — из GET /api/reports/monthly-grid
+    const { monthSlots: slots, payoutsByMonthInv: byMonthInv } = gridToSlots(
+      await fetchMonthlyGrid()
+    );
 
     const baseColumns = [
       { header: "ID", key: "id", width: 10 },
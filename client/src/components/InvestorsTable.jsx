@@ -1,6 +1,7 @@
 import React, { useMemo, useState, useEffect } from "react";
 import ExcelExporter from "./ExcelExporter";
 import InvestorRow from "./InvestorsTable/InvestorRow";
+import { useMonthSlots } from "../hooks/useMonthSlots";
 
 const MAX_VISIBLE_MONTH_SLOTS = 4;
 
@@ -40,49 +41,8 @@ export default function InvestorsTable({
   // === месячные колонки ===
   const [monthOffset, setMonthOffset] = useState(0);
 
-  const { monthSlots, payoutsByMonthInv } = useMemo(() => {
-    const byMonthInv = new Map();
-
-    payouts.forEach((p) => {
-       if (p.isTopup) return; 
-      if (!p.periodMonth) return;
-      if (!byMonthInv.has(p.periodMonth)) {
-        byMonthInv.set(p.periodMonth, new Map());
-      }
-
-      const invMap = byMonthInv.get(p.periodMonth);
-      const list = invMap.get(p.investorId) || [];
-      list.push(p);
-      invMap.set(p.investorId, list);
-    });
-
-    const months = Array.from(byMonthInv.entries())
-      .filter(([_, invMap]) => {
-        for (const list of invMap.values()) {
-          if (list.length > 0) return true;
-        }
-        return false;
-      })
-      .map(([month]) => month)
-      .sort();
-
-    const slots = [];
-
-    months.forEach((month) => {
-      const invMap = byMonthInv.get(month);
-      let maxLen = 0;
-
-      for (const list of invMap.values()) {
-        if (list.length > maxLen) maxLen = list.length;
-      }
-
-      for (let i = 0; i < maxLen; i++) {
-        slots.push({ month, index: i });
-      }
-    });
-
-    return { monthSlots: slots, payoutsByMonthInv: byMonthInv };
-  }, [payouts]);
+  // сетка месяцев считается на сервере; перезапрашиваем при смене операций
+  const { monthSlots, payoutsByMonthInv } = useMonthSlots(payouts);
 
   useEffect(() => {
     setMonthOffset((prev) => {
@@ -103,14 +103,14 @@ export default function InvestorRow({
           type="text"
           inputMode="numeric"
           value={localInvested}
-          onChange={(e) => {
-            const formatted = formatMoneyInput(e.target.value);
-            setLocalInvested(formatted);
-
-            const clean = formatted.replace(/\s/g, "");
-            const num = Number(clean) || 0;
-
-            debouncedSave(inv.id, { investedAmount: num });
+          onChange={(e) => setLocalInvested(formatMoneyInput(e.target.value))}
+          // каждое изменение вложенной суммы — отдельная корректировка
+          // на сервере, поэтому сохраняем по уходу из поля, а не на каждый ввод
+          onBlur={() => {
+            const num = Number(localInvested.replace(/\s/g, "")) || 0;
+            if (num !== Number(inv.investedAmount || 0)) {
+              onUpdateInvestor(inv.id, { investedAmount: num });
+            }
           }}
           className="w-full bg-transparent px-2 py-1 rounded-lg outline-none 
                      border border-transparent hover:border-slate-600 
@@ -1,6 +1,6 @@
 // useInvestData.js
 
-import { useEffect, useState, useCallback } from "react";
+import { useEffect, useState, useCallback, useRef } from "react";
 import {
   API_URL,
   fetchInvestors,
@@ -16,11 +16,32 @@ export function useInvestData() {
   const [payouts, setPayouts] = useState([]);
   const [percents, setPercents] = useState({});
 
+  // последняя известная версия каждого инвестора (для If-Match);
+  // в ref, чтобы updateInvestor не пересоздавался и видел свежие значения
+  const versions = useRef({});
+
+  const rememberVersions = (list) => {
+    list.forEach((inv) => {
+      versions.current[inv.id] = inv.version;
+    });
+  };
+
   // =============================
   //   ЗАГРУЗКА ДАННЫХ
   // =============================
   useEffect(() => {
-    fetchInvestors().then((d) => setInvestors(Array.isArray(d) ? d : []));
+    fetchInvestors().then((d) => {
+      const list = Array.isArray(d) ? d : [];
+      rememberVersions(list);
+      setInvestors(list);
+
+      // сохранённые на сервере ставки
+      const saved = {};
+      list.forEach((inv) => {
+        if (inv.monthlyPercent != null) saved[inv.id] = inv.monthlyPercent;
+      });
+      setPercents(saved);
+    });
 
     fetchPayouts().then((d) =>
       setPayouts(
@@ -111,27 +132,44 @@ export function useInvestData() {
     if (updates.investedAmount !== undefined)
       body.invested_amount = updates.investedAmount;
 
+    const version = versions.current[id];
+
     const res = await fetch(`${API_URL}/investors/${id}`, {
       method: "PUT",
       headers: {
         "Content-Type": "application/json",
+        "If-Match": version ? `"${version}"` : "*",
         ...(token ? { Authorization: `Bearer ${token}` } : {})
       },
       body: JSON.stringify(body)
     });
 
+    // инвестора уже изменил кто-то другой — показываем актуальные данные
+    if (res.status === 412) {
+      const list = await fetchInvestors();
+      rememberVersions(list);
+      setInvestors(list);
+
+      alert("Инвестора уже изменил другой пользователь. Данные обновлены — повторите правку.");
+      return;
+    }
+
     if (!res.ok) {
       console.error("❌ UPDATE INVESTOR FAILED:", await res.text());
       return;
     }
 
+    const saved = await res.json();
+    versions.current[id] = saved.version;
+
     setInvestors((prev) =>
       prev.map((i) =>
         i.id === id
           ? {
               ...i,
               fullName: updates.fullName ?? i.fullName,
-              investedAmount: updates.investedAmount ?? i.investedAmount
+              investedAmount: updates.investedAmount ?? i.investedAmount,
+              version: saved.version
             }
           : i
       )
@@ -144,15 +182,16 @@ export function useInvestData() {
   async function addInvestor() {
     await createInvestor("", 0);
     const list = await fetchInvestors();
+    rememberVersions(list);
     setInvestors(list);
   }
 
   // =============================
   //   СОХРАНЕНИЕ ВЫПЛАТЫ (ПРИБЫЛЬ)
   // =============================
-  async function savePayout({ investorId, month, amount, reinvest }) {
-    if (reinvest) await createReinvest(investorId, month, amount);
-    else await createTakeProfit(investorId, month, amount);
+  async function savePayout({ investorId, month, amount, reinvest, idempotencyKey }) {
+    if (reinvest) await createReinvest(investorId, month, amount, idempotencyKey);
+    else await createTakeProfit(investorId, month, amount, idempotencyKey);
 
     const fresh = await fetchPayouts();
     setPayouts(
@@ -166,8 +205,8 @@ export function useInvestData() {
   // =============================
   //   СНЯТИЕ КАПИТАЛА
   // =============================
-  async function withdrawCapital({ investorId, month, amount }) {
-    await createCapitalWithdraw(investorId, month, amount);
+  async function withdrawCapital({ investorId, month, amount, idempotencyKey }) {
+    await createCapitalWithdraw(investorId, month, amount, idempotencyKey);
 
     const fresh = await fetchPayouts();
     setPayouts(
@@ -0,0 +1,44 @@
+// useMonthSlots.js
+
+import { useEffect, useMemo, useState } from "react";
+import { fetchMonthlyGrid } from "../api/api";
+
+// Колонки месяцев для таблицы и Excel из серверной сетки
+// GET /api/reports/monthly-grid: у месяца столько слотов, сколько операций
+// у самого активного инвестора. Месяцы без операций пропускаются.
+export function gridToSlots(grid) {
+  const monthSlots = [];
+  const payoutsByMonthInv = new Map();
+
+  (grid?.months || []).forEach((m) => {
+    if (!m.slots) return;
+
+    const invMap = new Map();
+    m.investors.forEach((c) => invMap.set(c.investor_id, c.operations));
+    payoutsByMonthInv.set(m.month, invMap);
+
+    for (let i = 0; i < m.slots; i++) {
+      monthSlots.push({ month: m.month, index: i });
+    }
+  });
+
+  return { monthSlots, payoutsByMonthInv };
+}
+
+// refreshKey — при его смене сетка перезапрашивается (например, список операций)
+export function useMonthSlots(refreshKey) {
+  const [grid, setGrid] = useState(null);
+
+  useEffect(() => {
+    let cancelled = false;
+    fetchMonthlyGrid().then((g) => {
+      if (!cancelled) setGrid(g);
+    });
+    return () => {
+      cancelled = true;
+    };
+  }, [refreshKey]);
+
+  const slots = useMemo(() => gridToSlots(grid), [grid]);
+  return { grid, ...slots };
+}
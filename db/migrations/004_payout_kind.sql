@@ -0,0 +1,63 @@
+-- 004_payout_kind.sql
+-- Вместо четырёх независимых флагов — один тип операции.
+-- Старые флаги остаются на один релиз (см. конец файла).
+
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS kind TEXT;
+
+-- ⭐ переносим старые строки; если выставлено несколько флагов,
+--    приоритет такой же, как в расчёте баланса
+UPDATE payouts SET kind = CASE
+    WHEN reinvest              THEN 'reinvest'
+    WHEN is_topup              THEN 'topup'
+    WHEN is_withdrawal_capital THEN 'capital_withdrawal'
+    WHEN is_withdrawal_profit  THEN 'profit_withdrawal'
+    ELSE 'legacy'
+END
+WHERE kind IS NULL;
+
+ALTER TABLE payouts ALTER COLUMN kind SET NOT NULL;
+
+ALTER TABLE payouts DROP CONSTRAINT IF EXISTS payouts_kind_check;
+ALTER TABLE payouts ADD CONSTRAINT payouts_kind_check CHECK (kind IN (
+    'reinvest',
+    'profit_withdrawal',
+    'capital_withdrawal',
+    'topup',
+    'legacy'
+));
+
+-- ⭐ флаги не удаляем: сервер предыдущей версии читает и пишет только их,
+--    а при обновлении он ещё какое-то время работает рядом с новым.
+--    Триггер держит kind и флаги согласованными в обе стороны: запись
+--    без kind получает его из флагов, флаги всегда пересчитываются из kind.
+--    Колонки и триггер удаляются отдельной миграцией в следующем релизе,
+--    когда все экземпляры сервера обновлены.
+CREATE OR REPLACE FUNCTION payouts_sync_kind_flags() RETURNS trigger AS $$
+BEGIN
+    IF NEW.kind IS NULL OR (
+        TG_OP = 'UPDATE' AND NEW.kind = OLD.kind AND
+        (NEW.reinvest, NEW.is_topup, NEW.is_withdrawal_profit, NEW.is_withdrawal_capital)
+            IS DISTINCT FROM
+        (OLD.reinvest, OLD.is_topup, OLD.is_withdrawal_profit, OLD.is_withdrawal_capital)
+    ) THEN
+        NEW.kind := CASE
+            WHEN NEW.reinvest              THEN 'reinvest'
+            WHEN NEW.is_topup              THEN 'topup'
+            WHEN NEW.is_withdrawal_capital THEN 'capital_withdrawal'
+            WHEN NEW.is_withdrawal_profit  THEN 'profit_withdrawal'
+            ELSE 'legacy'
+        END;
+    END IF;
+
+    NEW.reinvest              := NEW.kind = 'reinvest';
+    NEW.is_topup              := NEW.kind = 'topup';
+    NEW.is_withdrawal_capital := NEW.kind = 'capital_withdrawal';
+    NEW.is_withdrawal_profit  := NEW.kind = 'profit_withdrawal';
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS payouts_kind_flags ON payouts;
+CREATE TRIGGER payouts_kind_flags
+    BEFORE INSERT OR UPDATE ON payouts
+    FOR EACH ROW EXECUTE FUNCTION payouts_sync_kind_flags();
@@ -0,0 +1,12 @@
+-- 005_investor_rates.sql
+-- Месячный процент инвестора с датой начала действия.
+
+CREATE TABLE IF NOT EXISTS investor_rates (
+    id SERIAL PRIMARY KEY,
+    investor_id INT NOT NULL REFERENCES investors(id) ON DELETE CASCADE,
+    monthly_percent NUMERIC(9,4) NOT NULL CHECK (monthly_percent >= 0),
+    effective_from DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    UNIQUE (investor_id, effective_from)
+);
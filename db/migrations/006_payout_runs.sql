@@ -0,0 +1,32 @@
+-- 006_payout_runs.sql
+-- Пакетная ежемесячная выплата по всем инвесторам.
+
+CREATE TABLE IF NOT EXISTS payout_runs (
+    id SERIAL PRIMARY KEY,
+    period_date DATE NOT NULL,
+    status TEXT NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'committed', 'rolled_back')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    committed_at TIMESTAMPTZ,
+    rolled_back_at TIMESTAMPTZ
+);
+
+ALTER TABLE payouts
+    ADD COLUMN IF NOT EXISTS run_id INT REFERENCES payout_runs(id);
+
+CREATE INDEX IF NOT EXISTS idx_payouts_run_id ON payouts(run_id);
+
+-- ⭐ строки пакета: сохраняются уже в черновике, чтобы commit провёл ровно то,
+--    что было показано в предпросмотре
+CREATE TABLE IF NOT EXISTS payout_run_items (
+    id SERIAL PRIMARY KEY,
+    run_id INT NOT NULL REFERENCES payout_runs(id) ON DELETE CASCADE,
+    investor_id INT NOT NULL REFERENCES investors(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('reinvest', 'profit_withdrawal')),
+    amount NUMERIC(18,2) NOT NULL,
+    capital NUMERIC(18,2) NOT NULL,
+    monthly_percent NUMERIC(9,4),
+    payout_id INT REFERENCES payouts(id) ON DELETE SET NULL,
+
+    UNIQUE (run_id, investor_id)
+);
@@ -0,0 +1,6 @@
+-- 007_profit_distributions.sql
+-- Пакет может быть создан распределением общей прибыли фонда за период.
+
+ALTER TABLE payout_runs
+    ADD COLUMN IF NOT EXISTS period_from DATE,
+    ADD COLUMN IF NOT EXISTS total_profit NUMERIC(18,2);
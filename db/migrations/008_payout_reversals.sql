@@ -0,0 +1,10 @@
+-- 008_payout_reversals.sql
+-- Ошибочные операции не редактируются и не удаляются, а сторнируются.
+
+ALTER TABLE payouts
+    ADD COLUMN IF NOT EXISTS reverses_id INT REFERENCES payouts(id),
+    ADD COLUMN IF NOT EXISTS replaces_id INT REFERENCES payouts(id);
+
+-- ⭐ у операции может быть не больше одного сторно
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payouts_reverses_id
+    ON payouts(reverses_id) WHERE reverses_id IS NOT NULL;
@@ -0,0 +1,19 @@
+-- 009_investor_soft_delete.sql
+-- Инвесторы архивируются / помечаются удалёнными вместо DELETE.
+
+ALTER TABLE investors
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active',
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+ALTER TABLE investors DROP CONSTRAINT IF EXISTS investors_status_check;
+ALTER TABLE investors ADD CONSTRAINT investors_status_check
+    CHECK (status IN ('active', 'archived', 'deleted'));
+
+CREATE INDEX IF NOT EXISTS idx_investors_status ON investors(status);
+
+-- ⭐ больше никакого каскада: удалить инвестора с операциями можно
+--    только явным purge, который сначала удаляет историю
+ALTER TABLE payouts DROP CONSTRAINT IF EXISTS payouts_investor_id_fkey;
+ALTER TABLE payouts ADD CONSTRAINT payouts_investor_id_fkey
+    FOREIGN KEY (investor_id) REFERENCES investors(id) ON DELETE RESTRICT;
@@ -0,0 +1,28 @@
+-- 010_workspaces.sql
+-- Рабочие пространства: пользователи видят только инвесторов своего пространства.
+-- Операции (payouts, ставки) принадлежат пространству через инвестора.
+
+CREATE TABLE IF NOT EXISTS workspaces (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- ⭐ основное пространство — сюда переносятся все существующие данные
+INSERT INTO workspaces (name)
+SELECT 'Default'
+WHERE NOT EXISTS (SELECT 1 FROM workspaces);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS workspace_id INT REFERENCES workspaces(id);
+UPDATE users SET workspace_id = (SELECT MIN(id) FROM workspaces) WHERE workspace_id IS NULL;
+ALTER TABLE users ALTER COLUMN workspace_id SET NOT NULL;
+
+ALTER TABLE investors ADD COLUMN IF NOT EXISTS workspace_id INT REFERENCES workspaces(id);
+UPDATE investors SET workspace_id = (SELECT MIN(id) FROM workspaces) WHERE workspace_id IS NULL;
+ALTER TABLE investors ALTER COLUMN workspace_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_investors_workspace ON investors(workspace_id, status);
+
+ALTER TABLE payout_runs ADD COLUMN IF NOT EXISTS workspace_id INT REFERENCES workspaces(id);
+UPDATE payout_runs SET workspace_id = (SELECT MIN(id) FROM workspaces) WHERE workspace_id IS NULL;
+ALTER TABLE payout_runs ALTER COLUMN workspace_id SET NOT NULL;
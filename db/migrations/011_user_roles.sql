@@ -0,0 +1,14 @@
+-- 011_user_roles.sql
+-- Роли пользователей: owner / accountant / viewer.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT;
+
+-- ⭐ у существующих пользователей были все права — оставляем их владельцами
+UPDATE users SET role = 'owner' WHERE role IS NULL;
+
+ALTER TABLE users ALTER COLUMN role SET NOT NULL;
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'viewer';
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check
+    CHECK (role IN ('owner', 'accountant', 'viewer'));
@@ -0,0 +1,18 @@
+-- 012_invites.sql
+-- Одноразовые приглашения вместо общего кода регистрации.
+-- В базе хранится только sha256 токена; сам токен показывается владельцу один раз.
+
+CREATE TABLE IF NOT EXISTS invites (
+    id SERIAL PRIMARY KEY,
+    workspace_id INT NOT NULL REFERENCES workspaces(id),
+    email TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'accountant', 'viewer')),
+    token_hash TEXT NOT NULL UNIQUE,
+    created_by INT REFERENCES users(id),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    used_by INT REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_invites_workspace ON invites(workspace_id, created_at);
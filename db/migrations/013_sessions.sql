@@ -0,0 +1,21 @@
+-- 013_sessions.sql
+-- Сессии с ротируемыми refresh-токенами и отзыв access-токенов.
+-- Храним только sha256 refresh-токена; previous_hash нужен, чтобы заметить
+-- повторное использование уже обменянного токена (признак утечки).
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_hash TEXT NOT NULL UNIQUE,
+    previous_hash TEXT,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_previous_hash ON sessions(previous_hash);
+
+-- ⭐ токены, выданные раньше этого момента, не принимаются ("выйти на всех устройствах")
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_revoked_at TIMESTAMPTZ;
@@ -0,0 +1,5 @@
+-- 015_login_lockout.sql
+-- Защита от подбора пароля: счётчик неудачных входов и временная блокировка.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_logins INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
@@ -0,0 +1,39 @@
+-- 017_audit_events.sql
+-- Журнал действий пользователей и автор операций.
+--
+-- Записи журнала только добавляются (UPDATE/DELETE запрещены триггером)
+-- и связаны в цепочку хешей внутри пространства: hash = sha256(prev_hash + запись).
+-- before/after хранятся как json (не jsonb), чтобы текст совпадал с захешированным.
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    workspace_id INT NOT NULL REFERENCES workspaces(id),
+    actor_id INT,
+    action TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id BIGINT,
+    before JSON,
+    after JSON,
+    ip TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    prev_hash TEXT NOT NULL,
+    hash TEXT NOT NULL UNIQUE
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_ws ON audit_events(workspace_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_entity ON audit_events(workspace_id, entity_type, entity_id);
+
+CREATE OR REPLACE FUNCTION audit_events_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_events_no_change ON audit_events;
+CREATE TRIGGER audit_events_no_change
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_immutable();
+
+-- ⭐ кто провёл операцию / создал пакет (у старых записей NULL)
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS created_by INT REFERENCES users(id);
+ALTER TABLE payout_runs ADD COLUMN IF NOT EXISTS created_by INT REFERENCES users(id);
@@ -0,0 +1,45 @@
+-- 018_invested_ledger.sql
+-- Вложенная сумма больше не хранится в investors: начальное вложение —
+-- операция 'deposit', каждое изменение — датированная 'adjustment'.
+
+ALTER TABLE payouts DROP CONSTRAINT IF EXISTS payouts_kind_check;
+ALTER TABLE payouts ADD CONSTRAINT payouts_kind_check CHECK (kind IN (
+    'reinvest',
+    'profit_withdrawal',
+    'capital_withdrawal',
+    'topup',
+    'deposit',
+    'adjustment',
+    'legacy'
+));
+
+-- ⭐ переносим invested_amount в начальные вложения.
+--    Дата — не позже самой ранней операции инвестора, чтобы балансы
+--    «на дату» в прошлом остались такими же, как до миграции.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'investors' AND column_name = 'invested_amount'
+    ) THEN
+        INSERT INTO payouts (investor_id, period_date, payout_amount, kind, created_at)
+        SELECT i.id,
+               LEAST(i.created_at::date, COALESCE(
+                   (SELECT MIN(p.period_date) FROM payouts p WHERE p.investor_id = i.id),
+                   i.created_at::date)),
+               i.invested_amount,
+               'deposit',
+               i.created_at
+        FROM investors i
+        WHERE i.invested_amount <> 0
+          AND NOT EXISTS (
+              SELECT 1 FROM payouts p
+              WHERE p.investor_id = i.id AND p.kind IN ('deposit', 'adjustment')
+          );
+
+        ALTER TABLE investors DROP COLUMN invested_amount;
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_payouts_invested
+    ON payouts(investor_id) WHERE kind IN ('deposit', 'adjustment');
@@ -0,0 +1,24 @@
+-- 019_idempotency_keys.sql
+-- Заголовок Idempotency-Key: повтор запроса с тем же ключом получает
+-- сохранённый ответ вместо повторного проведения операции.
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+
+    -- sha256 от метода, пути и тела запроса
+    request_hash TEXT NOT NULL,
+
+    -- NULL, пока первый запрос ещё выполняется
+    status_code INT,
+    content_type TEXT,
+    response_body BYTEA,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+
+    UNIQUE (user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created ON idempotency_keys(created_at);
@@ -0,0 +1,5 @@
+-- 020_investor_version.sql
+-- Версия строки инвестора для оптимистичной блокировки (ETag / If-Match):
+-- каждое изменение увеличивает version на единицу.
+
+ALTER TABLE investors ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,7 @@
+-- 021_payouts_indexes.sql
+-- Индексы под фильтры и keyset-пагинацию GET /api/payouts:
+-- сортировка по (period_date, id), по инвестору и по всему пространству.
+
+CREATE INDEX IF NOT EXISTS idx_payouts_investor_date ON payouts(investor_id, period_date, id);
+CREATE INDEX IF NOT EXISTS idx_payouts_period_date ON payouts(period_date, id);
+CREATE INDEX IF NOT EXISTS idx_payouts_investor_amount ON payouts(investor_id, payout_amount, id);
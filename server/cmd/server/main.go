@@ -3,6 +3,7 @@ package main
 import (
 	"invest/internal/config"
 	"invest/internal/db"
+	"invest/internal/mailer"
 	"invest/internal/repository"
 	httpHandlers "invest/internal/http"
 	"log"
@@ -13,9 +14,10 @@ func main() {
 	cfg := config.Load()          // Загружаем переменные окружения
 	pg := db.NewPostgres(cfg)     // Коннект к PostgreSQL
 	repo := repository.New(pg)    // Инициализация репозитория
+	mail := mailer.New(cfg)       // Почта: лог, файлы или SMTP
 
 	// Создаём HTTP-сервер с репозиторием и конфигом
-	srv := httpHandlers.NewServer(repo, cfg)
+	srv := httpHandlers.NewServer(repo, mail, cfg)
 
 	addr := ":" + cfg.APIPort
 	log.Printf("Starting API on %s", addr)
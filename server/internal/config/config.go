@@ -19,6 +19,20 @@ type Config struct {
 
 	JWTSecret      string
 	SecretRegCode  string
+
+	// адреса/подсети прокси (Caddy), которым доверяем X-Forwarded-For
+	TrustedProxies []string
+
+	// почта: MAILER=log|file|smtp
+	Mailer       string
+	MailDir      string
+	MailFrom     string
+	SMTPAddr     string
+	SMTPUser     string
+	SMTPPassword string
+
+	// адрес фронтенда — для ссылок в письмах
+	AppURL string
 }
 
 
@@ -33,7 +47,16 @@ func Load() *Config {
 
 
 		JWTSecret:     getEnv("JWT_SECRET", "change_me_jwt_secret"),
-		SecretRegCode: getEnv("SECRET_REG_CODE", "change_me_reg_code"),
+		// общий код регистрации; пусто — отключён, регистрация только по приглашениям
+		SecretRegCode: getEnv("SECRET_REG_CODE", ""),
+
+		Mailer:       getEnv("MAILER", "log"),
+		MailDir:      getEnv("MAIL_DIR", "./mail"),
+		MailFrom:     getEnv("MAIL_FROM", "noreply@localhost"),
+		SMTPAddr:     getEnv("SMTP_ADDR", ""),
+		SMTPUser:     getEnv("SMTP_USER", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		AppURL:       strings.TrimRight(getEnv("APP_URL", "http://localhost:5173"), "/"),
 
 	}
 
@@ -43,6 +66,9 @@ func Load() *Config {
 	// превращаем строку в слайс
 	cfg.CORSOrigins = parseCORS(corsRaw)
 
+	// "172.18.0.0/16, 10.0.0.5" — пусто: X-Forwarded-For игнорируется
+	cfg.TrustedProxies = parseList(getEnv("TRUSTED_PROXIES", ""))
+
 	log.Printf("Config loaded: DB=%s@%s:%s API_PORT=%s CORS=%v\n",
 		cfg.PostgresUser,
 		cfg.PostgresHost,
@@ -66,6 +92,16 @@ func getEnv(key, def string) string {
 	return val
 }
 
+func parseList(raw string) []string {
+	var out []string
+	for _, p := range strings.Split(raw, ",") {
+		if x := strings.TrimSpace(p); x != "" {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
 func parseCORS(raw string) []string {
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
@@ -0,0 +1,153 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"invest/internal/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//
+// ========================
+//      AUDIT
+// ========================
+//
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 500
+)
+
+// audit записывает действие текущего пользователя в журнал. ctx — контекст
+// из repo.InTx, в котором выполнено само действие: при ошибке записи
+// транзакция откатывается, и действие без записи в журнале не остаётся.
+func (s *Server) audit(ctx context.Context, r *http.Request, action, entityType string, entityID int64, before, after any) error {
+	return s.auditAs(ctx, r, workspaceID(r), userID(r), action, entityType, entityID, before, after)
+}
+
+// auditAs — для публичных маршрутов (регистрация, сброс пароля), где
+// пространство и пользователь известны не из токена.
+func (s *Server) auditAs(ctx context.Context, r *http.Request, ws, actor int64, action, entityType string, entityID int64, before, after any) error {
+	e := models.AuditEvent{
+		WorkspaceID: ws,
+		Action:      action,
+		EntityType:  entityType,
+		IP:          s.clientIP(r),
+	}
+	if actor != 0 {
+		e.ActorID = &actor
+	}
+	if entityID != 0 {
+		e.EntityID = &entityID
+	}
+
+	var err error
+	if e.Before, err = auditJSON(before); err == nil {
+		e.After, err = auditJSON(after)
+	}
+	if err == nil {
+		err = s.repo.AppendAudit(ctx, &e)
+	}
+	if err != nil {
+		return fmt.Errorf("audit %s: %w", action, err)
+	}
+	return nil
+}
+
+func auditJSON(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// handleAudit — GET /api/audit?action=&entity_type=&entity_id=&actor_id=&from=&to=&before_id=&limit=
+// from/to — даты YYYY-MM-DD включительно; записи идут от новых к старым,
+// следующая страница — before_id = id последней полученной записи.
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+
+	q := r.URL.Query()
+	f := models.AuditFilter{
+		Action:     q.Get("action"),
+		EntityType: q.Get("entity_type"),
+		Limit:      defaultAuditLimit,
+	}
+
+	ints := []struct {
+		name string
+		dst  *int64
+	}{
+		{"entity_id", &f.EntityID},
+		{"actor_id", &f.ActorID},
+		{"before_id", &f.BeforeID},
+	}
+	for _, p := range ints {
+		if v := q.Get(p.name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n <= 0 {
+				writeJSON(w, 400, errorResponse{Error: "invalid " + p.name})
+				return
+			}
+			*p.dst = n
+		}
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeJSON(w, 400, errorResponse{Error: "invalid limit"})
+			return
+		}
+		f.Limit = min(n, maxAuditLimit)
+	}
+
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			writeJSON(w, 400, errorResponse{Error: "invalid from, must be YYYY-MM-DD"})
+			return
+		}
+		f.From = &t
+	}
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			writeJSON(w, 400, errorResponse{Error: "invalid to, must be YYYY-MM-DD"})
+			return
+		}
+		t = t.AddDate(0, 0, 1)
+		f.To = &t
+	}
+
+	list, err := s.repo.ListAudit(r.Context(), workspaceID(r), f)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+	if list == nil {
+		list = []models.AuditEvent{}
+	}
+	writeJSON(w, 200, list)
+}
+
+// handleAuditVerify — GET /api/audit/verify: пересчёт цепочки хешей пространства.
+func (s *Server) handleAuditVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+
+	res, err := s.repo.VerifyAudit(r.Context(), workspaceID(r))
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, 200, res)
+}
@@ -2,8 +2,15 @@ package http
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"invest/internal/models"
+	"invest/internal/repository"
 	"net/http"
 
 	"strings"
@@ -13,16 +20,34 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Access-токен живёт недолго и продлевается через refresh-токен,
+// который хранится на сервере (таблица sessions) и меняется при каждом обмене.
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
 type authClaims struct {
-	UserID int64 `json:"sub"`
+	UserID      int64       `json:"sub"`
+	WorkspaceID int64       `json:"wid"`
+	Role        models.Role `json:"role"`
+	SessionID   int64       `json:"sid"`
+
+	// пространство требует 2FA, а она не настроена — доступна только её настройка
+	TwoFactorSetup bool `json:"tfa_setup,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func (s *Server) issueToken(userID int64) (string, error) {
+func (s *Server) issueToken(u *models.User, sessionID int64) (string, error) {
 	claims := authClaims{
-		UserID: userID,
+		UserID:      u.ID,
+		WorkspaceID: u.WorkspaceID,
+		Role:        u.Role,
+		SessionID:   sessionID,
+
+		TwoFactorSetup: u.NeedsTwoFactorSetup(),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(30 * 24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -30,6 +55,51 @@ func (s *Server) issueToken(userID int64) (string, error) {
 	return token.SignedString(s.jwtSecret)
 }
 
+// tokenResponse — ответ login/register/refresh.
+func tokenResponse(u *models.User, access, refresh string) map[string]any {
+	return map[string]any{
+		"token":        access,
+		"refreshToken": refresh,
+		"expiresIn":    int(accessTokenTTL.Seconds()),
+		"email":        u.Email,
+		"role":         u.Role,
+	}
+}
+
+// startSession открывает новую сессию и выдаёт пару токенов.
+func (s *Server) startSession(ctx context.Context, u *models.User) (map[string]any, error) {
+	refresh, err := newRandomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, err := s.repo.CreateSession(ctx, u.ID, hashToken(refresh), time.Now().Add(refreshTokenTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	access, err := s.issueToken(u, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return tokenResponse(u, access, refresh), nil
+}
+
+// newRandomToken — 32 случайных байта в base64url (refresh-токены, приглашения).
+func newRandomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken — в базе хранится только sha256 от токена.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
+
 // ====== REGISTRATION ======
 
 func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
@@ -39,19 +109,22 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Email      string `json:"email"`
-		Password   string `json:"password"`
+		Email       string `json:"email"`
+		Password    string `json:"password"`
+		InviteToken string `json:"inviteToken"`
+
+		// общий код регистрации — устаревший способ, работает, только если задан SECRET_REG_CODE
 		SecretCode string `json:"secretCode"`
+
+		// если задано — создаётся новое рабочее пространство,
+		// иначе пользователь попадает в основное
+		WorkspaceName string `json:"workspaceName"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSON(w, 400, errorResponse{Error: "invalid json"})
 		return
 	}
 
-	if req.SecretCode != s.secretRegCode {
-		writeJSON(w, 403, errorResponse{Error: "wrong secret code"})
-		return
-	}
 	if req.Email == "" || req.Password == "" {
 		writeJSON(w, 400, errorResponse{Error: "email and password required"})
 		return
@@ -78,21 +151,109 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 		Email:        req.Email,
 		PasswordHash: string(hash),
 	}
-	if err := s.repo.CreateUser(r.Context(), u); err != nil {
+
+	err = s.repo.InTx(r.Context(), func(ctx context.Context) error {
+		if err := s.registerUser(ctx, u, req.InviteToken, req.SecretCode, req.WorkspaceName); err != nil {
+			return err
+		}
+
+		// роль, пространство и его политику 2FA (для приглашённых) перечитываем из базы
+		fresh, err := s.repo.GetUserByID(ctx, u.ID)
+		if err != nil {
+			return err
+		}
+		if fresh != nil {
+			u = fresh
+		}
+		return s.auditAs(ctx, r, u.WorkspaceID, u.ID, "user.register", "user", u.ID, nil,
+			map[string]any{"email": u.Email, "role": u.Role})
+	})
+	switch {
+	case errors.Is(err, repository.ErrInviteInvalid),
+		errors.Is(err, repository.ErrInviteEmail),
+		errors.Is(err, errRegistrationClosed):
+		writeJSON(w, 403, errorResponse{Error: err.Error()})
+		return
+	case err != nil:
 		writeJSON(w, 500, errorResponse{Error: err.Error()})
 		return
 	}
 
-	token, err := s.issueToken(u.ID)
+	resp, err := s.startSession(r.Context(), u)
 	if err != nil {
 		writeJSON(w, 500, errorResponse{Error: "token error"})
 		return
 	}
 
-	writeJSON(w, 200, map[string]any{
-		"token": token,
-		"email": u.Email,
-	})
+	writeJSON(w, 200, resp)
+}
+
+var errRegistrationClosed = errors.New("registration requires an invite")
+
+// registerUser выбирает способ регистрации:
+//   - приглашение — пространство и роль берутся из него, токен погашается;
+//   - общий код (если включён) — новое или основное пространство, как раньше;
+//   - без кода — только самый первый пользователь системы (он станет владельцем).
+func (s *Server) registerUser(ctx context.Context, u *models.User, inviteToken, secretCode, workspaceName string) error {
+	closed := errRegistrationClosed
+
+	if inviteToken != "" {
+		err := s.repo.RegisterWithInvite(ctx, hashToken(inviteToken), u)
+		if !errors.Is(err, repository.ErrInviteInvalid) {
+			return err
+		}
+		// клиент может прислать общий код в поле приглашения — проверим и его
+		if secretCode == "" {
+			secretCode = inviteToken
+		}
+		closed = err
+	}
+
+	if s.sharedCodeOK(secretCode) {
+		if workspaceName != "" {
+			return s.repo.CreateUserInNewWorkspace(ctx, u, workspaceName)
+		}
+		return s.joinDefaultWorkspace(ctx, u)
+	}
+
+	n, err := s.repo.CountAllUsers(ctx)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return closed
+	}
+	return s.joinDefaultWorkspace(ctx, u)
+}
+
+// sharedCodeOK — общий код включён (SECRET_REG_CODE не пуст) и совпадает.
+func (s *Server) sharedCodeOK(code string) bool {
+	if s.secretRegCode == "" || code == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(code), []byte(s.secretRegCode)) == 1
+}
+
+// joinDefaultWorkspace добавляет пользователя в основное пространство:
+// первый пользователь становится владельцем, остальные — наблюдателями,
+// пока владелец не повысит роль.
+func (s *Server) joinDefaultWorkspace(ctx context.Context, u *models.User) error {
+	ws, err := s.repo.GetDefaultWorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	n, err := s.repo.CountUsers(ctx, ws)
+	if err != nil {
+		return err
+	}
+
+	u.WorkspaceID = ws
+	u.Role = models.RoleViewer
+	if n == 0 {
+		u.Role = models.RoleOwner
+	}
+	return s.repo.CreateUser(ctx, u)
 }
 
 // ====== LOGIN ======
@@ -118,32 +279,164 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if u == nil {
+		// хеш считается и для несуществующего email — по времени ответа
+		// нельзя понять, есть ли такой пользователь
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(req.Password))
 		writeJSON(w, 401, errorResponse{Error: "invalid credentials"})
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(req.Password)); err != nil {
+	passErr := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(req.Password))
+
+	// заблокированный вход отвечает так же, как неверный пароль (и после
+	// проверки хеша): отдельный 429 выдавал бы, что аккаунт существует
+	if u.LockedAt(time.Now()) {
+		writeJSON(w, 401, errorResponse{Error: "invalid credentials"})
+		return
+	}
+	if passErr != nil {
+		s.loginFailed(r.Context(), r, u)
 		writeJSON(w, 401, errorResponse{Error: "invalid credentials"})
 		return
 	}
 
-	token, err := s.issueToken(u.ID)
+	// с включённой 2FA полный токен выдаётся только после /api/login/2fa
+	if u.TOTPEnabled {
+		mfa, err := s.issueMFAToken(u)
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: "token error"})
+			return
+		}
+		// счётчик неудач сбросится только после второго фактора
+		writeJSON(w, 200, map[string]any{
+			"mfaRequired": true,
+			"mfaToken":    mfa,
+			"email":       u.Email,
+		})
+		return
+	}
+
+	s.loginSucceeded(r.Context(), u)
+
+	resp, err := s.startSession(r.Context(), u)
 	if err != nil {
 		writeJSON(w, 500, errorResponse{Error: "token error"})
 		return
 	}
 
-	writeJSON(w, 200, map[string]any{
-		"token": token,
-		"email": u.Email,
+	writeJSON(w, 200, resp)
+}
+
+// ====== REFRESH / LOGOUT ======
+
+// handleRefresh — POST /api/refresh {refreshToken}: обмен на новую пару токенов.
+// Каждый refresh-токен одноразовый; повторное предъявление отзывает сессию.
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, 400, errorResponse{Error: "invalid json"})
+		return
+	}
+	if req.RefreshToken == "" {
+		writeJSON(w, 400, errorResponse{Error: "refreshToken required"})
+		return
+	}
+
+	refresh, err := newRandomToken()
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: "token error"})
+		return
+	}
+
+	u, sessionID, err := s.repo.RotateSession(r.Context(),
+		hashToken(req.RefreshToken), hashToken(refresh), time.Now().Add(refreshTokenTTL))
+	if errors.Is(err, repository.ErrSessionInvalid) || errors.Is(err, repository.ErrSessionReused) {
+		writeJSON(w, 401, errorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	access, err := s.issueToken(u, sessionID)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: "token error"})
+		return
+	}
+
+	writeJSON(w, 200, tokenResponse(u, access, refresh))
+}
+
+// handleLogout — POST /api/logout: закрывает текущую сессию.
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+
+	if err := s.repo.RevokeSession(r.Context(), userID(r), sessionID(r)); err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// handleLogoutAll — POST /api/logout-all: закрывает все сессии пользователя
+// и отзывает все ранее выданные access-токены.
+func (s *Server) handleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+
+	err := s.repo.InTx(r.Context(), func(ctx context.Context) error {
+		if err := s.repo.RevokeAllSessions(ctx, userID(r)); err != nil {
+			return err
+		}
+		return s.audit(ctx, r, "user.logout_all", "user", userID(r), nil, nil)
 	})
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+	w.WriteHeader(204)
 }
 
 // ====== MIDDLEWARE ======
 
 type ctxKey int
 
-const userIDCtxKey ctxKey = 1
+const (
+	userIDCtxKey ctxKey = iota + 1
+	workspaceIDCtxKey
+	roleCtxKey
+	sessionIDCtxKey
+)
+
+func userID(r *http.Request) int64 {
+	id, _ := r.Context().Value(userIDCtxKey).(int64)
+	return id
+}
+
+func sessionID(r *http.Request) int64 {
+	id, _ := r.Context().Value(sessionIDCtxKey).(int64)
+	return id
+}
+
+// workspaceID — рабочее пространство текущего пользователя (кладётся в withAuth).
+// Все запросы к данным ограничиваются им.
+func workspaceID(r *http.Request) int64 {
+	id, _ := r.Context().Value(workspaceIDCtxKey).(int64)
+	return id
+}
 
 func (s *Server) withAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -162,13 +455,67 @@ func (s *Server) withAuth(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		// токены без пространства, роли или сессии (выданные до их появления) не принимаются
 		claims, ok := t.Claims.(*authClaims)
-		if !ok {
+		if !ok || claims.WorkspaceID == 0 || !claims.Role.Valid() ||
+			claims.SessionID == 0 || claims.IssuedAt == nil {
 			writeJSON(w, 401, errorResponse{Error: "invalid token"})
 			return
 		}
 
+		// сессия закрыта (logout) или все токены пользователя отозваны
+		active, err := s.repo.SessionActive(r.Context(), claims.UserID, claims.SessionID, claims.IssuedAt.Time)
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			return
+		}
+		if !active {
+			writeJSON(w, 401, errorResponse{Error: "token revoked"})
+			return
+		}
+
+		if claims.TwoFactorSetup && !twoFactorSetupAllowed(r.URL.Path) {
+			writeJSON(w, 403, errorResponse{Error: "two-factor setup required"})
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), userIDCtxKey, claims.UserID)
+		ctx = context.WithValue(ctx, workspaceIDCtxKey, claims.WorkspaceID)
+		ctx = context.WithValue(ctx, roleCtxKey, claims.Role)
+		ctx = context.WithValue(ctx, sessionIDCtxKey, claims.SessionID)
 		next(w, r.WithContext(ctx))
 	}
 }
+
+// twoFactorSetupAllowed — маршруты, доступные до настройки обязательной 2FA.
+func twoFactorSetupAllowed(path string) bool {
+	return path == "/api/me/2fa" || strings.HasPrefix(path, "/api/me/2fa/") ||
+		path == "/api/logout" || path == "/api/logout-all"
+}
+
+// withRole проверяет роль пользователя (ставится после withAuth):
+// чтение (GET/HEAD) доступно начиная с роли read, остальные методы — с write.
+func (s *Server) withRole(read, write models.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		need := write
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			need = read
+		}
+
+		if !requireRole(w, r, need) {
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requireRole — та же проверка внутри обработчика, когда один маршрут
+// обслуживает действия с разными правами. При отказе пишет 403.
+func requireRole(w http.ResponseWriter, r *http.Request, need models.Role) bool {
+	role, _ := r.Context().Value(roleCtxKey).(models.Role)
+	if !role.Allows(need) {
+		writeJSON(w, 403, errorResponse{Error: "forbidden: requires role " + string(need)})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"invest/internal/ledger"
+	"invest/internal/models"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+//
+// ========================
+//   PROFIT DISTRIBUTION
+// ========================
+//
+
+type distributionResponse struct {
+	*models.PayoutRun
+	Shares   []ledger.Share `json:"shares"`
+	Rounding string         `json:"rounding"`
+}
+
+// handleProfitDistributions — POST /api/profit-distributions
+//
+// Делит общую прибыль фонда за период между инвесторами пропорционально
+// капитало-дням (с учётом пополнений и снятий внутри периода) и создаёт
+// пакетную выплату на дату окончания периода.
+func (s *Server) handleProfitDistributions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+
+	ctx := r.Context()
+	ws := workspaceID(r)
+
+	var req struct {
+		From        string            `json:"from"`
+		To          string            `json:"to"`
+		TotalProfit models.Money      `json:"totalProfit"`
+		Kind        models.PayoutKind `json:"kind"`
+		Preview     bool              `json:"preview"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, 400, errorResponse{Error: "invalid json"})
+		return
+	}
+
+	from, err := time.Parse("2006-01-02", req.From)
+	if err != nil {
+		writeJSON(w, 400, errorResponse{Error: "invalid from, must be YYYY-MM-DD"})
+		return
+	}
+	to, err := time.Parse("2006-01-02", req.To)
+	if err != nil {
+		writeJSON(w, 400, errorResponse{Error: "invalid to, must be YYYY-MM-DD"})
+		return
+	}
+	if to.Before(from) {
+		writeJSON(w, 400, errorResponse{Error: "to must not be before from"})
+		return
+	}
+
+	if req.TotalProfit <= 0 {
+		writeJSON(w, 400, errorResponse{Error: "totalProfit must be positive"})
+		return
+	}
+
+	if req.Kind == "" {
+		req.Kind = models.KindReinvest
+	}
+	if !isProfitKind(req.Kind) {
+		writeJSON(w, 400, errorResponse{Error: "kind must be reinvest or profit_withdrawal"})
+		return
+	}
+
+	investors, err := s.repo.ListInvestors(ctx, ws, models.InvestorActive)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	payouts, err := s.repo.GetPayouts(ctx, ws)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	weights := make(map[int64]*big.Int, len(investors))
+	byID := make(map[int64]models.Investor, len(investors))
+	for _, inv := range investors {
+		weights[inv.ID] = ledger.CapitalDays(inv, payouts, from, to)
+		byID[inv.ID] = inv
+	}
+
+	shares := ledger.Distribute(req.TotalProfit, weights)
+	if len(shares) == 0 {
+		writeJSON(w, 422, errorResponse{Error: "no investor capital in this period"})
+		return
+	}
+
+	total := req.TotalProfit
+	run := &models.PayoutRun{
+		WorkspaceID: ws,
+		PeriodDate:  to,
+		PeriodFrom:  &from,
+		TotalProfit: &total,
+		Total:       total,
+		CreatedBy:   actorRef(r),
+	}
+	if !req.Preview {
+		run.Status = models.RunCommitted
+	}
+
+	for _, sh := range shares {
+		if sh.Amount.IsZero() {
+			continue
+		}
+		run.Items = append(run.Items, models.PayoutRunItem{
+			InvestorID: sh.InvestorID,
+			Kind:       req.Kind,
+			Amount:     sh.Amount,
+			Capital:    ledger.ComputeAt(byID[sh.InvestorID], payouts, to).CapitalNow,
+		})
+	}
+
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.CreatePayoutRun(ctx, run); err != nil {
+			return err
+		}
+		return s.audit(ctx, r, "profit_distribution.create", "payout_run", run.ID, nil, run)
+	})
+	if err != nil {
+		writePayoutError(w, err)
+		return
+	}
+
+	writeJSON(w, 201, distributionResponse{
+		PayoutRun: run,
+		Shares:    shares,
+		Rounding:  "floor to kopeck, leftover kopecks by largest remainder, ties by investor id",
+	})
+}
@@ -0,0 +1,383 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"invest/internal/ledger"
+	"invest/internal/models"
+	"invest/internal/xlsx"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// ========================
+//        EXPORTS
+// ========================
+//
+
+// exportParams — общие параметры выгрузок: период и набор инвесторов.
+type exportParams struct {
+	investors []models.Investor
+	payouts   []models.Payout // все операции пространства (нужны для остатков)
+	from, to  time.Time
+}
+
+// loadExportParams разбирает from / to (YYYY-MM-DD, включительно),
+// investor_id (через запятую) и status. По умолчанию — с первой операции
+// по сегодня, все инвесторы в статусе active. При ошибке ответ уже отправлен.
+func (s *Server) loadExportParams(w http.ResponseWriter, r *http.Request) (*exportParams, bool) {
+	ctx := r.Context()
+	ws := workspaceID(r)
+	q := r.URL.Query()
+
+	status := models.InvestorStatus(q.Get("status"))
+	if status == "" {
+		status = models.InvestorActive
+	}
+	if !status.Valid() {
+		writeJSON(w, 400, errorResponse{Error: "status must be active, archived or deleted"})
+		return nil, false
+	}
+
+	from, to, msg := parseDateRange(q)
+	if msg != "" {
+		writeJSON(w, 400, errorResponse{Error: msg})
+		return nil, false
+	}
+
+	ids := make(map[int64]bool)
+	if v := q.Get("investor_id"); v != "" {
+		for _, part := range strings.Split(v, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil || id <= 0 {
+				writeJSON(w, 400, errorResponse{Error: "invalid investor_id"})
+				return nil, false
+			}
+			ids[id] = true
+		}
+	}
+
+	list, err := s.repo.ListInvestors(ctx, ws, status)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return nil, false
+	}
+	payouts, err := s.repo.GetPayouts(ctx, ws)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return nil, false
+	}
+
+	p := &exportParams{payouts: payouts}
+	for _, inv := range list {
+		if len(ids) == 0 || ids[inv.ID] {
+			p.investors = append(p.investors, inv)
+		}
+	}
+
+	p.from, p.to = defaultDateRange(from, to, payouts)
+	if p.from.After(p.to) {
+		writeJSON(w, 400, errorResponse{Error: "from must not be after to"})
+		return nil, false
+	}
+	return p, true
+}
+
+// parseDateRange разбирает from / to (YYYY-MM-DD); msg — текст ошибки для 400.
+func parseDateRange(q url.Values) (from, to *time.Time, msg string) {
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &from}, {"to", &to}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				return nil, nil, "invalid " + p.name + ", must be YYYY-MM-DD"
+			}
+			*p.dst = &t
+		}
+	}
+	return from, to, ""
+}
+
+// defaultDateRange подставляет границы по умолчанию: to — сегодня,
+// from — дата первой операции из payouts (или to, если операций нет).
+func defaultDateRange(from, to *time.Time, payouts []models.Payout) (time.Time, time.Time) {
+	end := time.Now().UTC().Truncate(24 * time.Hour)
+	if to != nil {
+		end = *to
+	}
+	start := end
+	if first := firstPayoutDate(payouts); first != nil && first.Before(end) {
+		start = *first
+	}
+	if from != nil {
+		start = *from
+	}
+	return start, end
+}
+
+// handleExportXLSX — GET /api/exports/investors.xlsx
+//
+// Листы: «Сводка» (показатели инвесторов на дату to), «По месяцам»
+// (месячная сетка) и по листу-выписке на каждого инвестора. Показатели
+// сводки — формулы от итогов выписок по тем же правилам, что ledger.Compute.
+func (s *Server) handleExportXLSX(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+
+	p, ok := s.loadExportParams(w, r)
+	if !ok {
+		return
+	}
+
+	wb := xlsx.New()
+	summary := wb.AddSheet("Сводка")
+	monthly := wb.AddSheet("По месяцам")
+
+	// сначала выписки: сводка ссылается на их итоговые строки
+	totals := make(map[int64]ledgerTotals, len(p.investors))
+	for _, inv := range p.investors {
+		totals[inv.ID] = writeLedgerSheet(wb, inv, p)
+	}
+
+	writeSummarySheet(summary, p, totals)
+	writeMonthlySheet(monthly, p)
+
+	var buf bytes.Buffer
+	if err := wb.Write(&buf); err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	name := fmt.Sprintf("investors_%s_%s.xlsx", p.from.Format("2006-01-02"), p.to.Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	_, _ = buf.WriteTo(w)
+}
+
+// колонки выписки инвестора
+const (
+	lcDate = iota + 1
+	lcKind
+	lcAmount
+	lcInvested
+	lcReinvest
+	lcTopup
+	lcCapitalOut
+	lcProfitOut
+	lcCapital
+)
+
+// ledgerTotals — где на листе выписки лежит итоговая строка.
+type ledgerTotals struct {
+	sheet string
+	row   int
+}
+
+func money(m models.Money) xlsx.Cell { return xlsx.Number(m.String(), xlsx.StyleMoney) }
+
+// capitalFormula — капитал по правилам ledger.Compute:
+// вложено + реинвест + пополнения − снятый капитал.
+func capitalFormula(invested, reinvest, topup, capitalOut string) string {
+	return invested + "+" + reinvest + "+" + topup + "-" + capitalOut
+}
+
+// writeLedgerSheet — выписка инвестора: остаток на начало периода, операции
+// периода по колонкам типов, нарастающий капитал и итоговая строка.
+func writeLedgerSheet(wb *xlsx.Workbook, inv models.Investor, p *exportParams) ledgerTotals {
+	sh := wb.AddSheet(fmt.Sprintf("%d %s", inv.ID, inv.FullName))
+	sh.FreezeHeader()
+	for col, width := range map[int]float64{
+		lcDate: 12, lcKind: 22, lcAmount: 14, lcInvested: 14, lcReinvest: 14,
+		lcTopup: 14, lcCapitalOut: 16, lcProfitOut: 16, lcCapital: 16,
+	} {
+		sh.SetWidth(col, width)
+	}
+
+	sh.AddRow(
+		xlsx.Header("Дата"), xlsx.Header("Операция"), xlsx.Header("Сумма"),
+		xlsx.Header("Вложено"), xlsx.Header("Реинвест"), xlsx.Header("Пополнения"),
+		xlsx.Header("Снято капитала"), xlsx.Header("Снято прибыли"), xlsx.Header("Капитал"),
+	)
+
+	ref := func(col, row int) string { return xlsx.Ref(col, row) }
+	capitalAt := func(row int) string {
+		return capitalFormula(ref(lcInvested, row), ref(lcReinvest, row), ref(lcTopup, row), ref(lcCapitalOut, row))
+	}
+
+	// остаток на начало: всё, что было до from (включая операции без даты)
+	open := ledger.ComputeAt(inv, p.payouts, p.from.AddDate(0, 0, -1))
+	row := sh.NextRow()
+	sh.AddRow(
+		xlsx.Date(p.from), xlsx.Text("Остаток на начало"), xlsx.Empty(),
+		money(open.InvestedAmount), money(open.ReinvestedTotal), money(open.TopupsTotal),
+		money(open.WithdrawnCapital), money(open.WithdrawnProfit),
+		xlsx.Formula(capitalAt(row), open.CapitalNow.String(), xlsx.StyleMoney),
+	)
+	first := row
+	capital := open.CapitalNow
+
+	for _, op := range p.payouts {
+		if op.InvestorID != inv.ID || op.Voided() || op.PeriodDate == nil {
+			continue
+		}
+		if op.PeriodDate.Before(p.from) || op.PeriodDate.After(p.to) {
+			continue
+		}
+
+		cells := []xlsx.Cell{
+			xlsx.Date(*op.PeriodDate), xlsx.Text(op.Kind.Title()), money(op.PayoutAmount),
+			xlsx.Empty(), xlsx.Empty(), xlsx.Empty(), xlsx.Empty(), xlsx.Empty(),
+		}
+		switch op.Kind {
+		case models.KindDeposit, models.KindAdjustment:
+			cells[lcInvested-1] = money(op.PayoutAmount)
+		case models.KindReinvest:
+			cells[lcReinvest-1] = money(op.PayoutAmount)
+		case models.KindTopup:
+			cells[lcTopup-1] = money(op.PayoutAmount)
+		case models.KindCapitalWithdrawal:
+			cells[lcCapitalOut-1] = money(op.PayoutAmount.Abs())
+		case models.KindProfitWithdrawal:
+			cells[lcProfitOut-1] = money(op.PayoutAmount.Abs())
+		}
+
+		row = sh.NextRow()
+		capital = capital.Add(ledger.CapitalDelta(op))
+		cells = append(cells, xlsx.Formula(
+			ref(lcCapital, row-1)+"+"+capitalAt(row), capital.String(), xlsx.StyleMoney))
+		sh.AddRow(cells...)
+	}
+
+	// итоги: суммы колонок и капитал по той же формуле
+	last := sh.NextRow() - 1
+	b := ledger.ComputeAt(inv, p.payouts, p.to)
+	sum := func(col int, cached models.Money) xlsx.Cell {
+		return xlsx.Formula(fmt.Sprintf("SUM(%s:%s)", ref(col, first), ref(col, last)), cached.String(), xlsx.StyleMoneyBold)
+	}
+
+	row = sh.NextRow()
+	sh.AddRow(
+		xlsx.Date(p.to), xlsx.Header("Итого"), xlsx.Empty(),
+		sum(lcInvested, b.InvestedAmount), sum(lcReinvest, b.ReinvestedTotal), sum(lcTopup, b.TopupsTotal),
+		sum(lcCapitalOut, b.WithdrawnCapital), sum(lcProfitOut, b.WithdrawnProfit),
+		xlsx.Formula(capitalAt(row), b.CapitalNow.String(), xlsx.StyleMoneyBold),
+	)
+
+	return ledgerTotals{sheet: sh.Name(), row: row}
+}
+
+// writeSummarySheet — строка на инвестора: суммы по типам из итогов выписки
+// и производные показатели формулами (капитал, чистая прибыль, прибыль за всё время).
+func writeSummarySheet(sh *xlsx.Sheet, p *exportParams, totals map[int64]ledgerTotals) {
+	sh.FreezeHeader()
+	headers := []string{
+		"ID", "ФИО", "Ставка, %", "Вложено", "Реинвест", "Пополнения",
+		"Снято капитала", "Снято прибыли", "Капитал", "Чистая прибыль", "Прибыль за всё время",
+	}
+	cells := make([]xlsx.Cell, 0, len(headers))
+	for i, h := range headers {
+		cells = append(cells, xlsx.Header(h))
+		sh.SetWidth(i+1, 16)
+	}
+	sh.SetWidth(2, 32)
+	sh.AddRow(cells...)
+
+	col := xlsx.ColName
+	first := sh.NextRow()
+
+	for _, inv := range p.investors {
+		t := totals[inv.ID]
+		b := ledger.ComputeAt(inv, p.payouts, p.to)
+		row := sh.NextRow()
+		r := strconv.Itoa(row)
+
+		// из итоговой строки выписки
+		fromLedger := func(ledgerCol int, cached models.Money) xlsx.Cell {
+			return xlsx.Formula(xlsx.SheetRef(t.sheet, xlsx.Ref(ledgerCol, t.row)), cached.String(), xlsx.StyleMoney)
+		}
+
+		rate := xlsx.Empty()
+		if inv.MonthlyPercent != nil {
+			rate = xlsx.Number(inv.MonthlyPercent.String(), xlsx.StylePercent)
+		}
+
+		sh.AddRow(
+			xlsx.Int(inv.ID), xlsx.Text(inv.FullName), rate,
+			fromLedger(lcInvested, b.InvestedAmount),
+			fromLedger(lcReinvest, b.ReinvestedTotal),
+			fromLedger(lcTopup, b.TopupsTotal),
+			fromLedger(lcCapitalOut, b.WithdrawnCapital),
+			fromLedger(lcProfitOut, b.WithdrawnProfit),
+			xlsx.Formula(capitalFormula(col(4)+r, col(5)+r, col(6)+r, col(7)+r), b.CapitalNow.String(), xlsx.StyleMoney),
+			// чистая прибыль не уходит в минус
+			xlsx.Formula("MAX(0,"+col(5)+r+"-"+col(8)+r+")", b.NetProfit.String(), xlsx.StyleMoney),
+			xlsx.Formula(col(5)+r+"+"+col(8)+r, b.TotalProfitAllTime.String(), xlsx.StyleMoney),
+		)
+	}
+
+	last := sh.NextRow() - 1
+	if last < first {
+		return
+	}
+
+	total := []xlsx.Cell{xlsx.Empty(), xlsx.Header("Итого"), xlsx.Empty()}
+	for c := 4; c <= len(headers); c++ {
+		total = append(total, xlsx.Formula(fmt.Sprintf("SUM(%s%d:%s%d)", col(c), first, col(c), last), "", xlsx.StyleMoneyBold))
+	}
+	sh.AddRow(total...)
+}
+
+// writeMonthlySheet — месячная сетка: строка на месяц и инвестора.
+func writeMonthlySheet(sh *xlsx.Sheet, p *exportParams) {
+	sh.FreezeHeader()
+	headers := []string{
+		"Месяц", "ID", "ФИО", "Капитал на начало", "Вложено", "Реинвест",
+		"Пополнения", "Снято капитала", "Снято прибыли", "Капитал на конец", "Операций",
+	}
+	cells := make([]xlsx.Cell, 0, len(headers))
+	for i, h := range headers {
+		cells = append(cells, xlsx.Header(h))
+		sh.SetWidth(i+1, 16)
+	}
+	sh.SetWidth(3, 32)
+	sh.AddRow(cells...)
+
+	// операции позже to в сетку не попадают
+	payouts := make([]models.Payout, 0, len(p.payouts))
+	for _, op := range p.payouts {
+		if op.PeriodDate == nil || !op.PeriodDate.After(p.to) {
+			payouts = append(payouts, op)
+		}
+	}
+
+	names := make(map[int64]string, len(p.investors))
+	for _, inv := range p.investors {
+		names[inv.ID] = inv.FullName
+	}
+
+	col := xlsx.ColName
+	grid := ledger.BuildMonthlyGrid(p.investors, payouts, p.from, p.to)
+	for _, m := range grid.Months {
+		for _, c := range m.Investors {
+			r := strconv.Itoa(sh.NextRow())
+			sh.AddRow(
+				xlsx.Text(m.Month), xlsx.Int(c.InvestorID), xlsx.Text(names[c.InvestorID]),
+				money(c.CapitalStart),
+				money(c.Totals.Invested), money(c.Totals.Reinvest), money(c.Totals.Topup),
+				money(c.Totals.CapitalWithdrawal), money(c.Totals.ProfitWithdrawal),
+				xlsx.Formula(col(4)+r+"+"+capitalFormula(col(5)+r, col(6)+r, col(7)+r, col(8)+r),
+					c.CapitalEnd.String(), xlsx.StyleMoney),
+				xlsx.Int(int64(len(c.Operations))),
+			)
+		}
+	}
+}
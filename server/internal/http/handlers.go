@@ -1,8 +1,13 @@
 package http
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"invest/internal/ledger"
 	"invest/internal/models"
+	"invest/internal/repository"
 	"net/http"
 	"strconv"
 	"strings"
@@ -19,6 +24,41 @@ func writeJSON(w http.ResponseWriter, code int, v any) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// insufficientFundsResponse — тело 422 при попытке снять больше доступного.
+type insufficientFundsResponse struct {
+	Error      string            `json:"error"`
+	Code       string            `json:"code"`
+	InvestorID int64             `json:"investor_id"`
+	Kind       models.PayoutKind `json:"kind"`
+	Available  models.Money      `json:"available"`
+	Requested  models.Money      `json:"requested"`
+}
+
+// writePayoutError переводит ошибку проведения операции в ответ:
+// 422 при нехватке средств, 400 при неположительной сумме,
+// 404 если инвестор не найден, иначе 500.
+func writePayoutError(w http.ResponseWriter, err error) {
+	var ife *ledger.InsufficientFundsError
+
+	switch {
+	case errors.As(err, &ife):
+		writeJSON(w, 422, insufficientFundsResponse{
+			Error:      err.Error(),
+			Code:       "insufficient_funds",
+			InvestorID: ife.InvestorID,
+			Kind:       ife.Kind,
+			Available:  ife.Available,
+			Requested:  ife.Requested,
+		})
+	case errors.Is(err, models.ErrNonPositiveAmount):
+		writeJSON(w, 400, errorResponse{Error: err.Error()})
+	case errors.Is(err, sql.ErrNoRows):
+		writeJSON(w, 404, errorResponse{Error: "investor not found"})
+	default:
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+	}
+}
+
 //
 // ========================
 //      INVESTORS
@@ -27,29 +67,85 @@ func writeJSON(w http.ResponseWriter, code int, v any) {
 
 func (s *Server) handleInvestors(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	ws := workspaceID(r)
 
 	switch r.Method {
 
 	case http.MethodGet:
-		list, err := s.repo.ListInvestors(ctx)
+		// ?status=active|archived|deleted, по умолчанию — активные
+		status := models.InvestorStatus(r.URL.Query().Get("status"))
+		if status == "" {
+			status = models.InvestorActive
+		}
+		if !status.Valid() {
+			writeJSON(w, 400, errorResponse{Error: "status must be active, archived or deleted"})
+			return
+		}
+
+		list, err := s.repo.ListInvestors(ctx, ws, status)
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			return
+		}
+
+		// ?include=balance — сразу отдаём посчитанные балансы по всем инвесторам
+		if r.URL.Query().Get("include") != "balance" {
+			writeJSON(w, 200, list)
+			return
+		}
+
+		payouts, err := s.repo.GetPayouts(ctx, ws)
 		if err != nil {
 			writeJSON(w, 500, errorResponse{Error: err.Error()})
 			return
 		}
-		writeJSON(w, 200, list)
+
+		balances := ledger.ComputeAll(list, payouts)
+		out := make([]investorWithBalance, 0, len(list))
+		for _, inv := range list {
+			out = append(out, investorWithBalance{Investor: inv, Balance: balances[inv.ID]})
+		}
+		writeJSON(w, 200, out)
 
 	case http.MethodPost:
-		var inv models.Investor
-		_ = json.NewDecoder(r.Body).Decode(&inv)
+		var req struct {
+			FullName       string       `json:"full_name"`
+			InvestedAmount models.Money `json:"invested_amount"`
 
-		if inv.FullName == "" {
-			inv.FullName = ""
+			// дата начального вложения, по умолчанию сегодня
+			EffectiveFrom string `json:"effective_from"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, 400, errorResponse{Error: "invalid json"})
+			return
 		}
-		if inv.InvestedAmount == 0 {
-			inv.InvestedAmount = 0
+
+		if req.InvestedAmount < 0 {
+			writeJSON(w, 400, errorResponse{Error: "invested_amount must be non-negative"})
+			return
 		}
 
-		if err := s.repo.CreateInvestor(ctx, &inv); err != nil {
+		investedAt, ok := parseEffectiveFrom(w, req.EffectiveFrom)
+		if !ok {
+			return
+		}
+
+		inv := models.Investor{FullName: req.FullName, InvestedAmount: req.InvestedAmount}
+
+		err := s.repo.InTx(ctx, func(ctx context.Context) error {
+			deposit, err := s.repo.CreateInvestor(ctx, ws, &inv, investedAt, userID(r))
+			if err != nil {
+				return err
+			}
+			if err := s.audit(ctx, r, "investor.create", "investor", inv.ID, nil, inv); err != nil {
+				return err
+			}
+			if deposit != nil {
+				return s.audit(ctx, r, "payout.create", "payout", deposit.ID, nil, deposit)
+			}
+			return nil
+		})
+		if err != nil {
 			writeJSON(w, 500, errorResponse{Error: err.Error()})
 			return
 		}
@@ -61,22 +157,77 @@ func (s *Server) handleInvestors(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+type investorWithBalance struct {
+	models.Investor
+	Balance ledger.Balance `json:"balance"`
+}
+
 func (s *Server) handleInvestorByID(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	ws := workspaceID(r)
+
+	// /api/investors/{id} или /api/investors/{id}/{sub}
+	rest := strings.TrimPrefix(r.URL.Path, "/api/investors/")
+	idStr, sub, _ := strings.Cut(rest, "/")
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/investors/")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		writeJSON(w, 400, errorResponse{Error: "invalid investor id"})
 		return
 	}
 
+	switch sub {
+	case "":
+	case "balance":
+		s.handleInvestorBalance(w, r, id)
+		return
+	case "rate":
+		s.handleInvestorRate(w, r, id)
+		return
+	case "payouts":
+		s.handleInvestorPayouts(w, r, id)
+		return
+	case "statement.pdf":
+		s.handleInvestorStatement(w, r, id)
+		return
+	case "archive", "restore", "purge":
+		s.handleInvestorLifecycle(w, r, id, sub)
+		return
+	default:
+		writeJSON(w, 404, errorResponse{Error: "not found"})
+		return
+	}
+
 	switch r.Method {
 
+	case http.MethodGet:
+		inv, err := s.repo.GetInvestorByID(ctx, ws, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, 404, errorResponse{Error: "investor not found"})
+			return
+		}
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			return
+		}
+
+		w.Header().Set("ETag", investorETag(inv))
+		writeJSON(w, 200, inv)
+
 	case http.MethodPut:
+		// изменение только с If-Match: иначе два пользователя молча
+		// перезаписывают правки друг друга
+		version, ok := parseIfMatch(w, r)
+		if !ok {
+			return
+		}
+
 		var req struct {
-			FullName       *string  `json:"full_name"`
-			InvestedAmount *float64 `json:"invested_amount"`
+			FullName       *string       `json:"full_name"`
+			InvestedAmount *models.Money `json:"invested_amount"`
+
+			// с какой даты действует новая вложенная сумма, по умолчанию сегодня
+			EffectiveFrom string `json:"effective_from"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -84,32 +235,355 @@ func (s *Server) handleInvestorByID(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if err := s.repo.UpdateInvestor(ctx, id, req.FullName, req.InvestedAmount); err != nil {
+		before, err := s.repo.GetInvestorByID(ctx, ws, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, 404, errorResponse{Error: "investor not found"})
+			return
+		}
+		if err != nil {
 			writeJSON(w, 500, errorResponse{Error: err.Error()})
 			return
 		}
 
-		inv, err := s.repo.GetInvestorByID(ctx, id)
+		if req.InvestedAmount != nil && *req.InvestedAmount < 0 {
+			writeJSON(w, 400, errorResponse{Error: "invested_amount must be non-negative"})
+			return
+		}
+
+		effective, ok := parseEffectiveFrom(w, req.EffectiveFrom)
+		if !ok {
+			return
+		}
+
+		var inv *models.Investor
+		err = s.repo.InTx(ctx, func(ctx context.Context) error {
+			adj, err := s.repo.UpdateInvestor(ctx, ws, id, userID(r), repository.InvestorUpdate{
+				FullName:       req.FullName,
+				InvestedAmount: req.InvestedAmount,
+				EffectiveFrom:  effective,
+				Version:        version,
+			})
+			if err != nil {
+				return err
+			}
+
+			if inv, err = s.repo.GetInvestorByID(ctx, ws, id); err != nil {
+				return err
+			}
+			if err := s.audit(ctx, r, "investor.update", "investor", id, before, inv); err != nil {
+				return err
+			}
+			if adj != nil {
+				return s.audit(ctx, r, "payout.create", "payout", adj.ID, nil, adj)
+			}
+			return nil
+		})
+		if errors.Is(err, repository.ErrVersionConflict) {
+			s.writeVersionConflict(w, r, id)
+			return
+		}
 		if err != nil {
-			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			writePayoutError(w, err)
 			return
 		}
 
+		w.Header().Set("ETag", investorETag(inv))
 		writeJSON(w, 200, inv)
 
 	case http.MethodDelete:
-		if err := s.repo.DeleteInvestor(ctx, id); err != nil {
-			writeJSON(w, 500, errorResponse{Error: err.Error()})
+		// мягкое удаление: операции остаются, инвестора можно восстановить
+		s.handleInvestorLifecycle(w, r, id, "delete")
+
+	default:
+		w.WriteHeader(405)
+	}
+}
+
+// handleInvestorPayouts — GET /api/investors/{id}/payouts: операции инвестора
+// с теми же фильтрами и пагинацией, что у GET /api/payouts.
+func (s *Server) handleInvestorPayouts(w http.ResponseWriter, r *http.Request, id int64) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+
+	if _, err := s.repo.GetInvestorByID(r.Context(), workspaceID(r), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, 404, errorResponse{Error: "investor not found"})
 			return
 		}
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	s.writePayoutPage(w, r, id)
+}
+
+// investorETag — ETag инвестора: его версия в кавычках.
+func investorETag(inv *models.Investor) string {
+	return `"` + strconv.FormatInt(inv.Version, 10) + `"`
+}
+
+// parseIfMatch читает If-Match для PUT инвестора и возвращает ожидаемую
+// версию (0 для "*"). Без заголовка — 428; ETag, который не может совпасть
+// (слабый или не наш), — 412. В обоих случаях ответ уже отправлен.
+func parseIfMatch(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	h := strings.TrimSpace(r.Header.Get("If-Match"))
+	if h == "" {
+		writeJSON(w, 428, errorResponse{Error: "If-Match header required"})
+		return 0, false
+	}
+	if h == "*" {
+		return 0, true
+	}
+
+	v, err := strconv.ParseInt(strings.Trim(h, `"`), 10, 64)
+	if err != nil || v <= 0 || !strings.HasPrefix(h, `"`) || !strings.HasSuffix(h, `"`) {
+		writeJSON(w, 412, errorResponse{Error: "If-Match does not match current version"})
+		return 0, false
+	}
+	return v, true
+}
+
+// versionConflictResponse — тело 412: текущее состояние инвестора, чтобы
+// клиент мог показать чужие правки и повторить с новым ETag.
+type versionConflictResponse struct {
+	Error   string           `json:"error"`
+	Code    string           `json:"code"`
+	Current *models.Investor `json:"current,omitempty"`
+}
+
+func (s *Server) writeVersionConflict(w http.ResponseWriter, r *http.Request, id int64) {
+	resp := versionConflictResponse{
+		Error: repository.ErrVersionConflict.Error(),
+		Code:  "version_conflict",
+	}
+
+	if inv, err := s.repo.GetInvestorByID(r.Context(), workspaceID(r), id); err == nil {
+		resp.Current = inv
+		w.Header().Set("ETag", investorETag(inv))
+	}
+	writeJSON(w, 412, resp)
+}
+
+// handleInvestorLifecycle — POST /api/investors/{id}/archive|restore|purge
+// (и DELETE /api/investors/{id} как action "delete").
+//
+// purge — физическое удаление вместе с историей; только для удалённых
+// инвесторов с нулевым балансом.
+//
+// Все действия — только для владельца. Роль проверяется здесь, а не только
+// маршрутами в router.go: запросы вида DELETE /api/investors/5/ или
+// /api/investors/5%2Fpurge им не соответствуют и приходят сюда через
+// общий /api/investors/, открытый бухгалтеру.
+func (s *Server) handleInvestorLifecycle(w http.ResponseWriter, r *http.Request, id int64, action string) {
+	if r.Method != http.MethodPost && action != "delete" {
+		w.WriteHeader(405)
+		return
+	}
+	if !requireRole(w, r, models.RoleOwner) {
+		return
+	}
+
+	ctx := r.Context()
+	ws := workspaceID(r)
+
+	before, err := s.repo.GetInvestorByID(ctx, ws, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeJSON(w, 404, errorResponse{Error: "investor not found"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	var inv *models.Investor
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		switch action {
+		case "delete":
+			err = s.repo.SetInvestorStatus(ctx, ws, id, models.InvestorDeleted)
+		case "archive":
+			err = s.repo.SetInvestorStatus(ctx, ws, id, models.InvestorArchived)
+		case "restore":
+			err = s.repo.SetInvestorStatus(ctx, ws, id, models.InvestorActive)
+		case "purge":
+			err = s.repo.PurgeInvestor(ctx, ws, id)
+		}
+		if err != nil {
+			return err
+		}
+
+		if action == "purge" {
+			return s.audit(ctx, r, "investor.purge", "investor", id, before, nil)
+		}
+		if inv, err = s.repo.GetInvestorByID(ctx, ws, id); err != nil {
+			return err
+		}
+		return s.audit(ctx, r, "investor."+action, "investor", id, before, inv)
+	})
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeJSON(w, 404, errorResponse{Error: "investor not found"})
+		return
+	case errors.Is(err, repository.ErrNotDeleted), errors.Is(err, repository.ErrNonZeroBalance):
+		writeJSON(w, 409, errorResponse{Error: err.Error()})
+		return
+	case err != nil:
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	if action == "purge" {
+		writeJSON(w, 200, map[string]string{"message": "purged"})
+		return
+	}
 
+	if action == "delete" {
 		writeJSON(w, 200, map[string]string{"message": "deleted"})
+		return
+	}
+	writeJSON(w, 200, inv)
+}
+
+//
+// ========================
+//      BALANCE
+// ========================
+//
+
+func (s *Server) handleInvestorBalance(w http.ResponseWriter, r *http.Request, id int64) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+
+	ctx := r.Context()
+	ws := workspaceID(r)
+
+	inv, err := s.repo.GetInvestorByID(ctx, ws, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeJSON(w, 404, errorResponse{Error: "investor not found"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	payouts, err := s.repo.GetPayoutsByInvestor(ctx, ws, id)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, 200, ledger.Compute(*inv, payouts))
+}
+
+//
+// ========================
+//      RATE (МЕСЯЧНЫЙ ПРОЦЕНТ)
+// ========================
+//
+
+func (s *Server) handleInvestorRate(w http.ResponseWriter, r *http.Request, id int64) {
+	ctx := r.Context()
+	ws := workspaceID(r)
+
+	inv, err := s.repo.GetInvestorByID(ctx, ws, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, 404, errorResponse{Error: "investor not found"})
+			return
+		}
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	switch r.Method {
+
+	case http.MethodGet:
+		list, err := s.repo.ListInvestorRates(ctx, ws, id)
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, 200, list)
+
+	case http.MethodPut:
+		var req struct {
+			MonthlyPercent *models.Percent `json:"monthly_percent"`
+			EffectiveFrom  string          `json:"effective_from"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, 400, errorResponse{Error: "invalid json"})
+			return
+		}
+
+		if req.MonthlyPercent == nil || *req.MonthlyPercent < 0 || *req.MonthlyPercent > models.MaxPercent {
+			writeJSON(w, 400, errorResponse{Error: "monthly_percent must be between 0 and " + models.MaxPercent.String()})
+			return
+		}
+
+		// по умолчанию ставка действует с сегодняшнего дня
+		from, ok := parseEffectiveFrom(w, req.EffectiveFrom)
+		if !ok {
+			return
+		}
+
+		rt := models.InvestorRate{
+			InvestorID:     id,
+			MonthlyPercent: *req.MonthlyPercent,
+			EffectiveFrom:  from,
+		}
+
+		err := s.repo.InTx(ctx, func(ctx context.Context) error {
+			if err := s.repo.SetInvestorRate(ctx, ws, &rt); err != nil {
+				return err
+			}
+			return s.audit(ctx, r, "investor.rate", "investor", id,
+				map[string]any{"monthly_percent": inv.MonthlyPercent}, rt)
+		})
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			return
+		}
+
+		writeJSON(w, 200, rt)
 
 	default:
 		w.WriteHeader(405)
 	}
 }
 
+// defaultPayoutAmount — капитал на дату × действующая ставка.
+// ok=false, если инвестор не найден или ставка не задана.
+func (s *Server) defaultPayoutAmount(ctx context.Context, ws, investorID int64, at time.Time) (models.Money, bool, error) {
+	inv, err := s.repo.GetInvestorByID(ctx, ws, investorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	rt, err := s.repo.GetInvestorRateAt(ctx, ws, investorID, at)
+	if err != nil || rt == nil {
+		return 0, false, err
+	}
+
+	payouts, err := s.repo.GetPayoutsByInvestor(ctx, ws, investorID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	capital := ledger.ComputeAt(*inv, payouts, at).CapitalNow
+	return capital.MulPercent(rt.MonthlyPercent), true, nil
+}
+
 //
 // ========================
 //      TOPUP (ПОПОЛНЕНИЕ)
@@ -123,9 +597,9 @@ func (s *Server) handleTopup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		InvestorID int64   `json:"investorId"`
-		Date       string  `json:"date"`
-		Amount     float64 `json:"amount"`
+		InvestorID int64        `json:"investorId"`
+		Date       string       `json:"date"`
+		Amount     models.Money `json:"amount"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -139,16 +613,28 @@ func (s *Server) handleTopup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Amount <= 0 {
+		writeJSON(w, 400, errorResponse{Error: "amount must be positive"})
+		return
+	}
+
 	payout := models.Payout{
 		InvestorID:   req.InvestorID,
-		PeriodMonth:  nil,          // старое поле НЕ ЗАПОЛНЯЕМ
-		PeriodDate:   &period,      // новое поле
+		PeriodMonth:  nil,     // старое поле НЕ ЗАПОЛНЯЕМ
+		PeriodDate:   &period, // новое поле
 		PayoutAmount: req.Amount,
-		IsTopup:      true,
+		Kind:         models.KindTopup,
+		CreatedBy:    actorRef(r),
 	}
 
-	if err := s.repo.CreateTopup(r.Context(), &payout); err != nil {
-		writeJSON(w, 500, errorResponse{Error: err.Error()})
+	err = s.repo.InTx(r.Context(), func(ctx context.Context) error {
+		if err := s.repo.CreateTopup(ctx, workspaceID(r), &payout); err != nil {
+			return err
+		}
+		return s.audit(ctx, r, "payout.create", "payout", payout.ID, nil, payout)
+	})
+	if err != nil {
+		writePayoutError(w, err)
 		return
 	}
 
@@ -163,25 +649,24 @@ func (s *Server) handleTopup(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handlePayouts(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	ws := workspaceID(r)
 
 	switch r.Method {
 
 	case http.MethodGet:
-		list, err := s.repo.GetPayouts(ctx)
-		if err != nil {
-			writeJSON(w, 500, errorResponse{Error: err.Error()})
-			return
-		}
-		writeJSON(w, 200, list)
+		s.writePayoutPage(w, r, 0)
 
 	case http.MethodPost:
 		var req struct {
-			InvestorID          int64   `json:"investorId"`
-			Date                string  `json:"date"`
-			PayoutAmount        float64 `json:"payoutAmount"`
-			Reinvest            bool    `json:"reinvest"`
-			IsWithdrawalProfit  bool    `json:"isWithdrawalProfit"`
-			IsWithdrawalCapital bool    `json:"isWithdrawalCapital"`
+			InvestorID   int64             `json:"investorId"`
+			Date         string            `json:"date"`
+			PayoutAmount *models.Money     `json:"payoutAmount"`
+			Kind         models.PayoutKind `json:"kind"`
+
+			// устаревшие флаги — используются, только если kind не передан
+			Reinvest            bool `json:"reinvest"`
+			IsWithdrawalProfit  bool `json:"isWithdrawalProfit"`
+			IsWithdrawalCapital bool `json:"isWithdrawalCapital"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -195,19 +680,59 @@ func (s *Server) handlePayouts(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		kind := req.Kind
+		if kind == "" {
+			kind, err = models.KindFromFlags(req.Reinvest, req.IsWithdrawalProfit, req.IsWithdrawalCapital, false)
+			if err != nil {
+				writeJSON(w, 400, errorResponse{Error: err.Error()})
+				return
+			}
+		}
+		if !kind.Valid() {
+			writeJSON(w, 400, errorResponse{Error: "invalid payout kind"})
+			return
+		}
+
+		// сумма не передана — считаем от капитала и ставки инвестора
+		if req.PayoutAmount == nil {
+			if !isProfitKind(kind) {
+				writeJSON(w, 400, errorResponse{Error: "payoutAmount required"})
+				return
+			}
+
+			amount, ok, err := s.defaultPayoutAmount(ctx, ws, req.InvestorID, period)
+			if err != nil {
+				writeJSON(w, 500, errorResponse{Error: err.Error()})
+				return
+			}
+			if !ok {
+				writeJSON(w, 400, errorResponse{Error: "payoutAmount required: investor has no rate for this date"})
+				return
+			}
+			req.PayoutAmount = &amount
+		}
+
 		p := models.Payout{
-			InvestorID:          req.InvestorID,
-			PeriodMonth:         nil,     // старое поле не используется
-			PeriodDate:          &period, // новое поле
-			PayoutAmount:        req.PayoutAmount,
-			Reinvest:            req.Reinvest,
-			IsWithdrawalProfit:  req.IsWithdrawalProfit,
-			IsWithdrawalCapital: req.IsWithdrawalCapital,
-			IsTopup:             false,
+			InvestorID:   req.InvestorID,
+			PeriodMonth:  nil,     // старое поле не используется
+			PeriodDate:   &period, // новое поле
+			PayoutAmount: *req.PayoutAmount,
+			Kind:         kind,
+			CreatedBy:    actorRef(r),
+		}
+		if err := p.CheckAmount(); err != nil {
+			writeJSON(w, 400, errorResponse{Error: "payoutAmount must be non-zero, and positive for reinvest"})
+			return
 		}
 
-		if err := s.repo.CreatePayout(ctx, &p); err != nil {
-			writeJSON(w, 500, errorResponse{Error: err.Error()})
+		err = s.repo.InTx(ctx, func(ctx context.Context) error {
+			if err := s.repo.CreatePayout(ctx, ws, &p); err != nil {
+				return err
+			}
+			return s.audit(ctx, r, "payout.create", "payout", p.ID, nil, p)
+		})
+		if err != nil {
+			writePayoutError(w, err)
 			return
 		}
 
@@ -217,3 +742,128 @@ func (s *Server) handlePayouts(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(405)
 	}
 }
+
+//
+// ========================
+//   СТОРНО / ИСПРАВЛЕНИЕ
+// ========================
+//
+
+// handlePayoutByID — POST /api/payouts/{id}/reverse, POST /api/payouts/{id}/correct
+func (s *Server) handlePayoutByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ws := workspaceID(r)
+
+	rest := strings.TrimPrefix(r.URL.Path, "/api/payouts/")
+	idStr, action, _ := strings.Cut(rest, "/")
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeJSON(w, 400, errorResponse{Error: "invalid payout id"})
+		return
+	}
+
+	if action != "reverse" && action != "correct" {
+		writeJSON(w, 404, errorResponse{Error: "not found"})
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+
+	if action == "reverse" {
+		var rev *models.Payout
+		err := s.repo.InTx(ctx, func(ctx context.Context) error {
+			var err error
+			if rev, err = s.repo.ReversePayout(ctx, ws, id, userID(r)); err != nil {
+				return err
+			}
+			return s.audit(ctx, r, "payout.reverse", "payout", id, nil, rev)
+		})
+		if err != nil {
+			writeReversalError(w, err)
+			return
+		}
+		writeJSON(w, 201, rev)
+		return
+	}
+
+	var req struct {
+		PayoutAmount *models.Money `json:"payoutAmount"`
+		Date         string        `json:"date"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, 400, errorResponse{Error: "invalid json"})
+		return
+	}
+
+	var date *time.Time
+	if req.Date != "" {
+		t, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			writeJSON(w, 400, errorResponse{Error: "invalid date, must be YYYY-MM-DD"})
+			return
+		}
+		date = &t
+	}
+
+	if req.PayoutAmount == nil && date == nil {
+		writeJSON(w, 400, errorResponse{Error: "payoutAmount or date required"})
+		return
+	}
+
+	var resp map[string]any
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		rev, corrected, err := s.repo.CorrectPayout(ctx, ws, id, userID(r), req.PayoutAmount, date)
+		if err != nil {
+			return err
+		}
+		resp = map[string]any{
+			"reversal": rev,
+			"payout":   corrected,
+		}
+		return s.audit(ctx, r, "payout.correct", "payout", id, nil, resp)
+	})
+	if err != nil {
+		writeReversalError(w, err)
+		return
+	}
+
+	writeJSON(w, 201, resp)
+}
+
+// parseEffectiveFrom разбирает необязательную дату YYYY-MM-DD (пустая — сегодня).
+// При ошибке сам отвечает 400 и возвращает ok=false.
+func parseEffectiveFrom(w http.ResponseWriter, s string) (time.Time, bool) {
+	if s == "" {
+		return time.Now().UTC().Truncate(24 * time.Hour), true
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		writeJSON(w, 400, errorResponse{Error: "invalid effective_from, must be YYYY-MM-DD"})
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// actorRef — текущий пользователь для created_by.
+func actorRef(r *http.Request) *int64 {
+	id := userID(r)
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+func writeReversalError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrPayoutReversed), errors.Is(err, repository.ErrReversalEntry):
+		writeJSON(w, 409, errorResponse{Error: err.Error()})
+	case errors.Is(err, sql.ErrNoRows):
+		writeJSON(w, 404, errorResponse{Error: "payout not found"})
+	default:
+		writePayoutError(w, err)
+	}
+}
@@ -0,0 +1,143 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"invest/internal/repository"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+//
+// ========================
+//    IDEMPOTENCY-KEY
+// ========================
+//
+
+const (
+	// сколько хранится ответ на запрос с ключом
+	idempotencyTTL = 24 * time.Hour
+
+	// через сколько незавершённый резерв ключа считается брошенным:
+	// процесс мог упасть, не успев ни сохранить ответ, ни снять резерв
+	idempotencyLease = 10 * time.Minute
+
+	maxIdempotencyKeyLen = 255
+	// лимит тела для обычных JSON-запросов; импорт передаёт свой
+	maxIdempotentBody = 1 << 20
+)
+
+// withIdempotency — поддержка заголовка Idempotency-Key для POST-запросов,
+// проводящих деньги. Первый запрос выполняется и его ответ сохраняется;
+// повтор с тем же ключом и тем же телом получает сохранённый ответ
+// (с заголовком Idempotent-Replayed: true), с другим телом — 409.
+// Ответы 5xx не сохраняются, паника в обработчике тоже снимает резерв:
+// такой запрос можно повторить с тем же ключом.
+// Запросы без заголовка обрабатываются как обычно.
+// maxBody — лимит тела запроса: оно читается целиком, чтобы посчитать отпечаток.
+func (s *Server) withIdempotency(maxBody int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || r.Method != http.MethodPost {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			writeJSON(w, 400, errorResponse{Error: "Idempotency-Key is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+		if err != nil {
+			writeJSON(w, 400, errorResponse{Error: "request body is too large"})
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		uid := userID(r)
+
+		saved, err := s.repo.ClaimIdempotencyKey(ctx, uid, key, requestHash(r, body), idempotencyTTL, idempotencyLease)
+		switch {
+		case errors.Is(err, repository.ErrIdempotencyMismatch),
+			errors.Is(err, repository.ErrIdempotencyInProgress):
+			writeJSON(w, 409, errorResponse{Error: err.Error()})
+			return
+		case err != nil:
+			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			return
+		case saved != nil:
+			if saved.ContentType != "" {
+				w.Header().Set("Content-Type", saved.ContentType)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(saved.StatusCode)
+			_, _ = w.Write(saved.Body)
+			return
+		}
+
+		// ответ к моменту записи уже отправлен — сохраняем его, даже если клиент ушёл
+		bg := context.WithoutCancel(ctx)
+
+		defer func() {
+			if p := recover(); p != nil {
+				if err := s.repo.ReleaseIdempotencyKey(bg, uid, key); err != nil {
+					log.Printf("idempotency key %q: %v", key, err)
+				}
+				panic(p)
+			}
+		}()
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+
+		if rec.status >= 500 {
+			err = s.repo.ReleaseIdempotencyKey(bg, uid, key)
+		} else {
+			err = s.repo.CompleteIdempotencyKey(bg, uid, key, repository.IdempotentResponse{
+				StatusCode:  rec.status,
+				ContentType: rec.Header().Get("Content-Type"),
+				Body:        rec.body.Bytes(),
+			})
+		}
+		if err != nil {
+			log.Printf("idempotency key %q: %v", key, err)
+		}
+	}
+}
+
+// requestHash — отпечаток запроса: тот же ключ с другим методом, путём,
+// параметрами (например, ?dry_run у импорта) или телом считается другим запросом.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder пропускает ответ клиенту и запоминает код и тело.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(code int) {
+	if !rec.wroteHeader {
+		rec.status = code
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
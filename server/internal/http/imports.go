@@ -0,0 +1,250 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"invest/internal/importer"
+	"invest/internal/models"
+	"invest/internal/repository"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//
+// ========================
+//         IMPORTS
+// ========================
+//
+
+// максимальный размер загружаемого файла
+const maxImportSize = 10 << 20
+
+type importInvestor struct {
+	ID       int64  `json:"id,omitempty"` // после проведения
+	FullName string `json:"full_name"`
+}
+
+type importResponse struct {
+	DryRun bool `json:"dry_run"`
+
+	Rows         int              `json:"rows"`       // строк с операциями в файле
+	Operations   int              `json:"operations"` // сколько проводится
+	NewInvestors []importInvestor `json:"new_investors"`
+
+	Errors   []importer.RowError `json:"errors"`
+	Warnings []importer.RowError `json:"warnings"`
+
+	Payouts []models.Payout `json:"payouts,omitempty"` // созданные операции
+}
+
+// handleImports — POST /api/imports[?dry_run=true][&allow_duplicates=true]
+//
+// Тело — CSV или XLSX (в том числе книга из GET /api/exports/investors.xlsx):
+// как есть или полем file в multipart/form-data. Колонки ищутся по заголовкам
+// (ФИО / investor, investor_id, Дата / date, Операция / kind, Сумма / amount);
+// свои заголовки — параметром mapping, JSON вида {"date": "Дата платежа"}.
+//
+// dry_run=true только проверяет файл и возвращает ошибки по строкам. Без него
+// файл проводится целиком в одной транзакции — или, при любой ошибке, не
+// проводится совсем (422 с тем же списком ошибок).
+//
+// Строка, точно повторяющая уже проведённую операцию, — ошибка: так повторная
+// загрузка того же файла не удваивает операции. allow_duplicates=true
+// проводит такие строки с предупреждением.
+func (s *Server) handleImports(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+
+	ctx := r.Context()
+	ws := workspaceID(r)
+	q := r.URL.Query()
+
+	dryRun := false
+	if v := q.Get("dry_run"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeJSON(w, 400, errorResponse{Error: "invalid dry_run"})
+			return
+		}
+		dryRun = b
+	}
+	allowDuplicates := false
+	if v := q.Get("allow_duplicates"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeJSON(w, 400, errorResponse{Error: "invalid allow_duplicates"})
+			return
+		}
+		allowDuplicates = b
+	}
+
+	data, mappingJSON, msg := readImportFile(w, r)
+	if msg != "" {
+		writeJSON(w, 400, errorResponse{Error: msg})
+		return
+	}
+	if v := q.Get("mapping"); v != "" {
+		mappingJSON = v
+	}
+
+	var mapping importer.Mapping
+	if mappingJSON != "" {
+		if err := json.Unmarshal([]byte(mappingJSON), &mapping); err != nil {
+			writeJSON(w, 400, errorResponse{Error: "invalid mapping, expected {\"field\": \"column title\"}"})
+			return
+		}
+		if err := mapping.Validate(); err != nil {
+			writeJSON(w, 400, errorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	rows, rowErrs, err := importer.Parse(data, mapping)
+	if err != nil {
+		writeJSON(w, 400, errorResponse{Error: err.Error()})
+		return
+	}
+
+	// сопоставляем с действующими и архивными инвесторами
+	var investors []models.Investor
+	for _, status := range []models.InvestorStatus{models.InvestorActive, models.InvestorArchived} {
+		list, err := s.repo.ListInvestors(ctx, ws, status)
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			return
+		}
+		investors = append(investors, list...)
+	}
+	payouts, err := s.repo.GetPayouts(ctx, ws)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	plan := importer.BuildPlan(rows, investors, payouts, allowDuplicates)
+
+	resp := importResponse{
+		DryRun:       dryRun,
+		Rows:         len(rows) + len(rowErrs),
+		Operations:   len(plan.Operations),
+		NewInvestors: make([]importInvestor, 0, len(plan.NewInvestors)),
+		Errors:       append(rowErrs, plan.Errors...),
+		Warnings:     plan.Warnings,
+	}
+	for _, name := range plan.NewInvestors {
+		resp.NewInvestors = append(resp.NewInvestors, importInvestor{FullName: name})
+	}
+	if resp.Errors == nil {
+		resp.Errors = []importer.RowError{}
+	}
+	if resp.Warnings == nil {
+		resp.Warnings = []importer.RowError{}
+	}
+
+	switch {
+	case dryRun:
+		writeJSON(w, 200, resp)
+		return
+	case len(resp.Errors) > 0:
+		resp.Operations = 0
+		writeJSON(w, 422, resp)
+		return
+	case len(plan.Operations) == 0:
+		writeJSON(w, 400, errorResponse{Error: "file has no operations to import"})
+		return
+	}
+
+	batch := &repository.ImportBatch{NewInvestors: plan.NewInvestors}
+	for _, op := range plan.Operations {
+		batch.Operations = append(batch.Operations, repository.ImportOperation{
+			Payout:      op.Payout,
+			NewInvestor: op.NewInvestor,
+		})
+	}
+
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		created, err := s.repo.ImportOperations(ctx, ws, userID(r), batch)
+		if err != nil {
+			return err
+		}
+
+		for i, inv := range created {
+			resp.NewInvestors[i].ID = inv.ID
+			if err := s.audit(ctx, r, "investor.create", "investor", inv.ID, nil, inv); err != nil {
+				return err
+			}
+		}
+		resp.Payouts = make([]models.Payout, len(batch.Operations))
+		ids := make([]int64, len(batch.Operations))
+		for i, op := range batch.Operations {
+			resp.Payouts[i] = op.Payout
+			ids[i] = op.Payout.ID
+		}
+		return s.audit(ctx, r, "import.commit", "import", 0, nil, map[string]any{
+			"operations":    len(ids),
+			"payout_ids":    ids,
+			"new_investors": resp.NewInvestors,
+		})
+	})
+	var importErr *repository.ImportError
+	if errors.As(err, &importErr) {
+		// данные изменились между разбором файла и проведением
+		row := plan.Operations[importErr.Index].Row
+		msg := importErr.Err.Error()
+		if errors.Is(importErr.Err, sql.ErrNoRows) {
+			msg = "investor not found"
+		}
+		resp.Operations = 0
+		resp.Errors = []importer.RowError{{Sheet: row.Sheet, Row: row.Line, Error: msg}}
+		writeJSON(w, 409, resp)
+		return
+	}
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, 201, resp)
+}
+
+// readImportFile читает файл из тела запроса: multipart-поле file (и поле
+// mapping, если есть) или всё тело целиком. msg — текст ошибки для 400.
+func readImportFile(w http.ResponseWriter, r *http.Request) (data []byte, mapping, msg string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, "", "file is too large, at most 10 MB"
+		}
+		if len(data) == 0 {
+			return nil, "", "file is empty"
+		}
+		return data, "", ""
+	}
+
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		return nil, "", "invalid multipart body or file is too large"
+	}
+	defer r.MultipartForm.RemoveAll()
+	f, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, "", "file field is required"
+	}
+	defer f.Close()
+
+	data, err = io.ReadAll(f)
+	if err != nil {
+		return nil, "", err.Error()
+	}
+	if len(data) == 0 {
+		return nil, "", "file is empty"
+	}
+	return data, r.FormValue("mapping"), ""
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"invest/internal/models"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//
+// ========================
+//      INVITES (owner)
+// ========================
+//
+
+const (
+	defaultInviteTTL = 72 * time.Hour
+	maxInviteTTL     = 30 * 24 * time.Hour
+)
+
+// handleInvites — GET /api/invites, POST /api/invites
+func (s *Server) handleInvites(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ws := workspaceID(r)
+
+	switch r.Method {
+
+	case http.MethodGet:
+		list, err := s.repo.ListInvites(ctx, ws)
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, 200, list)
+
+	case http.MethodPost:
+		var req struct {
+			Email    string      `json:"email"`
+			Role     models.Role `json:"role"`
+			TTLHours int         `json:"ttlHours"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, 400, errorResponse{Error: "invalid json"})
+			return
+		}
+
+		req.Email = strings.TrimSpace(req.Email)
+		if req.Email == "" {
+			writeJSON(w, 400, errorResponse{Error: "email required"})
+			return
+		}
+		if !req.Role.Valid() {
+			writeJSON(w, 400, errorResponse{Error: "role must be owner, accountant or viewer"})
+			return
+		}
+
+		// сравниваем в часах: большое значение переполнило бы time.Duration
+		if req.TTLHours > int(maxInviteTTL/time.Hour) {
+			writeJSON(w, 400, errorResponse{Error: "ttlHours must be at most 720"})
+			return
+		}
+		ttl := defaultInviteTTL
+		if req.TTLHours > 0 {
+			ttl = time.Duration(req.TTLHours) * time.Hour
+		}
+
+		token, err := newRandomToken()
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: "token error"})
+			return
+		}
+
+		inv := models.Invite{
+			WorkspaceID: ws,
+			Email:       req.Email,
+			Role:        req.Role,
+			CreatedBy:   userID(r),
+			ExpiresAt:   time.Now().Add(ttl),
+		}
+
+		err = s.repo.InTx(ctx, func(ctx context.Context) error {
+			if err := s.repo.CreateInvite(ctx, &inv, hashToken(token)); err != nil {
+				return err
+			}
+			return s.audit(ctx, r, "invite.create", "invite", inv.ID, nil, inv)
+		})
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			return
+		}
+
+		// токен показывается один раз — дальше его нельзя получить из API
+		writeJSON(w, 201, map[string]any{
+			"invite": inv,
+			"token":  token,
+		})
+
+	default:
+		w.WriteHeader(405)
+	}
+}
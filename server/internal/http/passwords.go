@@ -0,0 +1,209 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"invest/internal/mailer"
+	"invest/internal/repository"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//
+// ========================
+//      PASSWORDS
+// ========================
+//
+
+const (
+	minPasswordLength = 8
+	passwordResetTTL  = time.Hour
+)
+
+func validatePassword(p string) error {
+	if len([]rune(p)) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	return nil
+}
+
+// handleChangePassword — POST /api/me/password {currentPassword, newPassword}.
+// Остальные сессии пользователя закрываются, текущая остаётся.
+func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, 400, errorResponse{Error: "invalid json"})
+		return
+	}
+	if err := validatePassword(req.NewPassword); err != nil {
+		writeJSON(w, 400, errorResponse{Error: err.Error()})
+		return
+	}
+
+	u, err := s.repo.GetUserByID(r.Context(), userID(r))
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+	if u == nil {
+		writeJSON(w, 404, errorResponse{Error: "user not found"})
+		return
+	}
+	if checkLocked(w, u) {
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		s.loginFailed(r.Context(), r, u)
+		writeJSON(w, 403, errorResponse{Error: "current password is incorrect"})
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), 12)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: "hash error"})
+		return
+	}
+
+	err = s.repo.InTx(r.Context(), func(ctx context.Context) error {
+		if err := s.repo.ChangePassword(ctx, u.ID, sessionID(r), string(hash)); err != nil {
+			return err
+		}
+		return s.audit(ctx, r, "user.password_change", "user", u.ID, nil, nil)
+	})
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// handleForgotPassword — POST /api/password/forgot {email}.
+// Ответ всегда 202, чтобы по нему нельзя было узнать, есть ли такой пользователь.
+func (s *Server) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, 400, errorResponse{Error: "invalid json"})
+		return
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		writeJSON(w, 400, errorResponse{Error: "email required"})
+		return
+	}
+
+	// письмо отправляется в фоне: время ответа не зависит от того, найден ли пользователь
+	go s.sendPasswordReset(context.Background(), email)
+
+	writeJSON(w, 202, map[string]any{"status": "if the account exists, a reset link has been sent"})
+}
+
+func (s *Server) sendPasswordReset(ctx context.Context, email string) {
+	u, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Printf("password reset: lookup %s: %v", email, err)
+		return
+	}
+	if u == nil {
+		return
+	}
+
+	token, err := newRandomToken()
+	if err != nil {
+		log.Printf("password reset: token: %v", err)
+		return
+	}
+
+	if err := s.repo.CreatePasswordReset(ctx, u.ID, hashToken(token), time.Now().Add(passwordResetTTL)); err != nil {
+		log.Printf("password reset: save token for user %d: %v", u.ID, err)
+		return
+	}
+
+	link := s.appURL + "/reset-password?token=" + url.QueryEscape(token)
+	msg := mailer.Message{
+		To:      u.Email,
+		Subject: "Сброс пароля",
+		Body: "Для сброса пароля перейдите по ссылке (действует 1 час):\n\n" + link +
+			"\n\nЕсли вы не запрашивали сброс, просто проигнорируйте это письмо.\n",
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Printf("password reset: send to %s: %v", u.Email, err)
+	}
+}
+
+// handleResetPassword — POST /api/password/reset {token, newPassword}.
+// Все сессии пользователя закрываются, блокировка входа снимается.
+func (s *Server) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+
+	var req struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, 400, errorResponse{Error: "invalid json"})
+		return
+	}
+	if req.Token == "" {
+		writeJSON(w, 400, errorResponse{Error: "token required"})
+		return
+	}
+	if err := validatePassword(req.NewPassword); err != nil {
+		writeJSON(w, 400, errorResponse{Error: err.Error()})
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), 12)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: "hash error"})
+		return
+	}
+
+	err = s.repo.InTx(r.Context(), func(ctx context.Context) error {
+		uid, err := s.repo.ResetPassword(ctx, hashToken(req.Token), string(hash))
+		if err != nil {
+			return err
+		}
+		u, err := s.repo.GetUserByID(ctx, uid)
+		if err != nil || u == nil {
+			return err
+		}
+		return s.auditAs(ctx, r, u.WorkspaceID, u.ID, "user.password_reset", "user", u.ID, nil, nil)
+	})
+	if errors.Is(err, repository.ErrResetInvalid) {
+		writeJSON(w, 400, errorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(204)
+}
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"invest/internal/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// ========================
+//    ПОИСК ОПЕРАЦИЙ
+// ========================
+//
+
+const (
+	defaultPayoutLimit = 100
+	maxPayoutLimit     = 1000
+)
+
+// writePayoutPage — GET /api/payouts и GET /api/investors/{id}/payouts.
+//
+// Параметры: investor_id, from / to (YYYY-MM-DD, включительно),
+// kind (через запятую), min_amount / max_amount (со знаком),
+// sort (date | -date | amount | -amount), limit, cursor.
+// Тело — массив операций; если есть следующая страница, её курсор
+// приходит в заголовке X-Next-Cursor.
+func (s *Server) writePayoutPage(w http.ResponseWriter, r *http.Request, investorID int64) {
+	f, msg := parsePayoutFilter(r.URL.Query())
+	if msg != "" {
+		writeJSON(w, 400, errorResponse{Error: msg})
+		return
+	}
+	if investorID != 0 {
+		f.InvestorID = investorID
+	}
+
+	list, more, err := s.repo.ListPayouts(r.Context(), workspaceID(r), f)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+	if list == nil {
+		list = []models.Payout{}
+	}
+
+	if more {
+		w.Header().Set("X-Next-Cursor", models.CursorAfter(list[len(list)-1], f.Sort).Encode())
+	}
+	writeJSON(w, 200, list)
+}
+
+// parsePayoutFilter разбирает параметры запроса; msg — текст ошибки для 400.
+func parsePayoutFilter(q url.Values) (f models.PayoutFilter, msg string) {
+	f.Sort = models.SortDateAsc
+	f.Limit = defaultPayoutLimit
+
+	if v := q.Get("investor_id"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return f, "invalid investor_id"
+		}
+		f.InvestorID = n
+	}
+
+	dates := []struct {
+		name string
+		dst  **time.Time
+	}{
+		{"from", &f.From},
+		{"to", &f.To},
+	}
+	for _, d := range dates {
+		if v := q.Get(d.name); v != "" {
+			t, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				return f, "invalid " + d.name + ", must be YYYY-MM-DD"
+			}
+			*d.dst = &t
+		}
+	}
+
+	if v := q.Get("kind"); v != "" {
+		for _, k := range strings.Split(v, ",") {
+			kind := models.PayoutKind(strings.TrimSpace(k))
+			if !kind.Known() {
+				return f, "invalid kind: " + string(kind)
+			}
+			f.Kinds = append(f.Kinds, kind)
+		}
+	}
+
+	amounts := []struct {
+		name string
+		dst  **models.Money
+	}{
+		{"min_amount", &f.MinAmount},
+		{"max_amount", &f.MaxAmount},
+	}
+	for _, a := range amounts {
+		if v := q.Get(a.name); v != "" {
+			m, err := models.ParseMoney(v)
+			if err != nil {
+				return f, "invalid " + a.name
+			}
+			*a.dst = &m
+		}
+	}
+
+	if v := q.Get("sort"); v != "" {
+		f.Sort = models.PayoutSort(v)
+		if !f.Sort.Valid() {
+			return f, "invalid sort, must be date, -date, amount or -amount"
+		}
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return f, "invalid limit"
+		}
+		f.Limit = min(n, maxPayoutLimit)
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		c, err := models.ParsePayoutCursor(v, f.Sort)
+		if err != nil {
+			return f, err.Error()
+		}
+		f.After = c
+	}
+
+	return f, ""
+}
@@ -0,0 +1,237 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"invest/internal/ledger"
+	"invest/internal/models"
+	"invest/internal/repository"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// ========================
+//      PAYOUT RUNS
+// ========================
+//
+
+type payoutRunOverride struct {
+	InvestorID int64             `json:"investorId"`
+	Amount     *models.Money     `json:"amount"`
+	Kind       models.PayoutKind `json:"kind"`
+	Skip       bool              `json:"skip"`
+}
+
+type payoutRunSkipped struct {
+	InvestorID int64  `json:"investor_id"`
+	Reason     string `json:"reason"`
+}
+
+type payoutRunResponse struct {
+	*models.PayoutRun
+	Skipped []payoutRunSkipped `json:"skipped,omitempty"`
+}
+
+// handlePayoutRuns — POST /api/payout-runs
+//
+// Считает выплату по всем инвесторам на дату: капитал на дату × действующая ставка.
+// preview=true сохраняет черновик без операций, иначе всё проводится сразу одной транзакцией.
+func (s *Server) handlePayoutRuns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+
+	ctx := r.Context()
+	ws := workspaceID(r)
+
+	var req struct {
+		Date      string              `json:"date"`
+		Kind      models.PayoutKind   `json:"kind"`
+		Preview   bool                `json:"preview"`
+		Overrides []payoutRunOverride `json:"overrides"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, 400, errorResponse{Error: "invalid json"})
+		return
+	}
+
+	period, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		writeJSON(w, 400, errorResponse{Error: "invalid date, must be YYYY-MM-DD"})
+		return
+	}
+
+	if req.Kind == "" {
+		req.Kind = models.KindReinvest
+	}
+	if !isProfitKind(req.Kind) {
+		writeJSON(w, 400, errorResponse{Error: "kind must be reinvest or profit_withdrawal"})
+		return
+	}
+
+	overrides := make(map[int64]payoutRunOverride, len(req.Overrides))
+	for _, ov := range req.Overrides {
+		if ov.Kind != "" && !isProfitKind(ov.Kind) {
+			writeJSON(w, 400, errorResponse{Error: "override kind must be reinvest or profit_withdrawal"})
+			return
+		}
+		if ov.Amount != nil && *ov.Amount < 0 {
+			writeJSON(w, 400, errorResponse{Error: "override amount must not be negative"})
+			return
+		}
+		overrides[ov.InvestorID] = ov
+	}
+
+	investors, err := s.repo.ListInvestors(ctx, ws, models.InvestorActive)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	payouts, err := s.repo.GetPayouts(ctx, ws)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	rates, err := s.repo.ListRatesAt(ctx, ws, period)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	run := &models.PayoutRun{WorkspaceID: ws, PeriodDate: period, CreatedBy: actorRef(r)}
+	if !req.Preview {
+		run.Status = models.RunCommitted
+	}
+	var skipped []payoutRunSkipped
+
+	known := make(map[int64]bool, len(investors))
+	for _, inv := range investors {
+		known[inv.ID] = true
+		ov := overrides[inv.ID]
+
+		if ov.Skip {
+			skipped = append(skipped, payoutRunSkipped{InvestorID: inv.ID, Reason: "skipped"})
+			continue
+		}
+
+		item := models.PayoutRunItem{
+			InvestorID: inv.ID,
+			Kind:       req.Kind,
+			Capital:    ledger.ComputeAt(inv, payouts, period).CapitalNow,
+		}
+		if ov.Kind != "" {
+			item.Kind = ov.Kind
+		}
+
+		switch pct, ok := rates[inv.ID]; {
+		case ov.Amount != nil:
+			item.Amount = *ov.Amount
+		case ok:
+			item.MonthlyPercent = &pct
+			item.Amount = item.Capital.MulPercent(pct)
+		default:
+			skipped = append(skipped, payoutRunSkipped{InvestorID: inv.ID, Reason: "no rate"})
+			continue
+		}
+
+		if item.Amount.IsZero() {
+			skipped = append(skipped, payoutRunSkipped{InvestorID: inv.ID, Reason: "zero amount"})
+			continue
+		}
+
+		run.Items = append(run.Items, item)
+		run.Total = run.Total.Add(item.Amount)
+	}
+
+	for id := range overrides {
+		if !known[id] {
+			writeJSON(w, 400, errorResponse{Error: "unknown investor in overrides: " + strconv.FormatInt(id, 10)})
+			return
+		}
+	}
+
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.CreatePayoutRun(ctx, run); err != nil {
+			return err
+		}
+		return s.audit(ctx, r, "payout_run.create", "payout_run", run.ID, nil, run)
+	})
+	if err != nil {
+		writePayoutError(w, err)
+		return
+	}
+
+	writeJSON(w, 201, payoutRunResponse{PayoutRun: run, Skipped: skipped})
+}
+
+// handlePayoutRunByID — GET /api/payout-runs/{id}, POST /api/payout-runs/{id}/commit|rollback
+func (s *Server) handlePayoutRunByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ws := workspaceID(r)
+
+	rest := strings.TrimPrefix(r.URL.Path, "/api/payout-runs/")
+	idStr, action, _ := strings.Cut(rest, "/")
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeJSON(w, 400, errorResponse{Error: "invalid run id"})
+		return
+	}
+
+	var run *models.PayoutRun
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		run, err = s.repo.GetPayoutRun(ctx, ws, id)
+	case action == "commit" && r.Method == http.MethodPost:
+		err = s.repo.InTx(ctx, func(ctx context.Context) error {
+			var err error
+			if run, err = s.repo.CommitPayoutRun(ctx, ws, id, userID(r)); err != nil {
+				return err
+			}
+			return s.audit(ctx, r, "payout_run.commit", "payout_run", id, nil, run)
+		})
+	case action == "rollback" && r.Method == http.MethodPost:
+		err = s.repo.InTx(ctx, func(ctx context.Context) error {
+			var err error
+			if run, err = s.repo.RollbackPayoutRun(ctx, ws, id, userID(r)); err != nil {
+				return err
+			}
+			return s.audit(ctx, r, "payout_run.rollback", "payout_run", id, nil, run)
+		})
+	case action == "" || action == "commit" || action == "rollback":
+		w.WriteHeader(405)
+		return
+	default:
+		writeJSON(w, 404, errorResponse{Error: "not found"})
+		return
+	}
+
+	var ife *ledger.InsufficientFundsError
+
+	switch {
+	case errors.As(err, &ife), errors.Is(err, models.ErrNonPositiveAmount):
+		writePayoutError(w, err)
+	case errors.Is(err, sql.ErrNoRows):
+		writeJSON(w, 404, errorResponse{Error: "payout run not found"})
+	case errors.Is(err, repository.ErrRunStatus):
+		writeJSON(w, 409, errorResponse{Error: err.Error()})
+	case err != nil:
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+	default:
+		writeJSON(w, 200, payoutRunResponse{PayoutRun: run})
+	}
+}
+
+func isProfitKind(k models.PayoutKind) bool {
+	return k == models.KindReinvest || k == models.KindProfitWithdrawal
+}
@@ -0,0 +1,241 @@
+package http
+
+import (
+	"context"
+	"invest/internal/models"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//
+// ========================
+//      RATE LIMITING
+// ========================
+//
+
+// ipLimiter — token bucket на IP клиента: burst запросов сразу,
+// дальше по одному раз в interval.
+type ipLimiter struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	burst    float64
+	interval time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	seen   time.Time
+}
+
+func newIPLimiter(burst int, interval time.Duration) *ipLimiter {
+	return &ipLimiter{
+		buckets:  make(map[string]*bucket),
+		burst:    float64(burst),
+		interval: interval,
+	}
+}
+
+// allow списывает токен. Если токенов нет — false и через сколько появится следующий.
+func (l *ipLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// старые корзины уже полные — их можно просто забыть
+	if len(l.buckets) > 10000 {
+		full := l.interval * time.Duration(l.burst)
+		for k, b := range l.buckets {
+			if now.Sub(b.seen) > full {
+				delete(l.buckets, k)
+			}
+		}
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, seen: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+float64(now.Sub(b.seen))/float64(l.interval))
+	b.seen = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) * float64(l.interval))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// withRateLimit ограничивает частоту запросов с одного IP (публичные маршруты входа).
+func (s *Server) withRateLimit(l *ipLimiter, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip := s.clientIP(r)
+
+		if ok, wait := l.allow(ip, time.Now()); !ok {
+			writeTooManyRequests(w, wait)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func writeTooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	secs := int(math.Ceil(wait.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	writeJSON(w, 429, errorResponse{Error: "too many attempts, try again later"})
+}
+
+// clientIP — адрес клиента. X-Forwarded-For учитывается, только если запрос
+// пришёл от доверенного прокси: идём справа налево и берём первый
+// недоверенный адрес (левые значения клиент может подделать).
+func (s *Server) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !s.trustedProxy(host) {
+		return host
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(hops[i])
+		if net.ParseIP(ip) == nil {
+			break
+		}
+		host = ip
+		if !s.trustedProxy(ip) {
+			break
+		}
+	}
+	return host
+}
+
+func (s *Server) trustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range s.trustedProxies {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies принимает как подсети ("172.18.0.0/16"), так и отдельные адреса.
+func parseTrustedProxies(list []string) []*net.IPNet {
+	var out []*net.IPNet
+	for _, entry := range list {
+		p := entry
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip != nil && ip.To4() != nil {
+				p += "/32"
+			} else {
+				p += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			log.Printf("config: ignoring invalid TRUSTED_PROXIES entry %q: %v", entry, err)
+			continue
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+//
+// ========================
+//      ACCOUNT LOCKOUT
+// ========================
+//
+
+const (
+	// первые неудачные попытки без задержки, дальше пауза растёт: 1с, 2с, 4с, ...
+	loginBackoffAfter = 3
+
+	// после стольких неудач подряд вход блокируется надолго
+	lockoutThreshold = 10
+	lockoutDuration  = 30 * time.Minute
+)
+
+// loginBackoff — на сколько заблокировать вход после n-й неудачи подряд.
+func loginBackoff(n int) time.Duration {
+	switch {
+	case n >= lockoutThreshold:
+		return lockoutDuration
+	case n < loginBackoffAfter:
+		return 0
+	}
+	return time.Second << (n - loginBackoffAfter)
+}
+
+// loginFailed учитывает неверный пароль или код 2FA и при необходимости блокирует вход.
+func (s *Server) loginFailed(ctx context.Context, r *http.Request, u *models.User) {
+	n, err := s.repo.RecordLoginFailure(ctx, u.ID)
+	if err != nil {
+		log.Printf("auth: record login failure for user %d: %v", u.ID, err)
+		return
+	}
+
+	d := loginBackoff(n)
+	if d == 0 {
+		return
+	}
+	if err := s.repo.LockUser(ctx, u.ID, time.Now().Add(d)); err != nil {
+		log.Printf("auth: lock user %d: %v", u.ID, err)
+		return
+	}
+
+	if n >= lockoutThreshold {
+		log.Printf("auth: account %s (id %d) locked for %s after %d failed attempts, last from %s",
+			u.Email, u.ID, d, n, s.clientIP(r))
+	}
+}
+
+// loginSucceeded сбрасывает счётчик неудач.
+func (s *Server) loginSucceeded(ctx context.Context, u *models.User) {
+	if u.FailedLogins == 0 && u.LockedUntil == nil {
+		return
+	}
+	if err := s.repo.ResetLoginFailures(ctx, u.ID); err != nil {
+		log.Printf("auth: reset login failures for user %d: %v", u.ID, err)
+	}
+}
+
+// dummyPasswordHash — хеш для сравнения, когда пользователя нет: та же
+// стоимость bcrypt, что у настоящих паролей.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("invalid-password"), 12)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// checkLocked отвечает 429, если вход пользователя временно заблокирован.
+// Только для уже опознанного пользователя (2FA, смена пароля): /api/login
+// отвечает на блокировку как на неверный пароль.
+func checkLocked(w http.ResponseWriter, u *models.User) bool {
+	now := time.Now()
+	if !u.LockedAt(now) {
+		return false
+	}
+	writeTooManyRequests(w, u.LockedUntil.Sub(now))
+	return true
+}
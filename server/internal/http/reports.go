@@ -0,0 +1,105 @@
+package http
+
+import (
+	"invest/internal/ledger"
+	"invest/internal/models"
+	"net/http"
+	"time"
+)
+
+//
+// ========================
+//        REPORTS
+// ========================
+//
+
+// максимальная длина месячной сетки — 20 лет
+const maxGridMonths = 240
+
+// handleMonthlyGrid — GET /api/reports/monthly-grid?from=YYYY-MM&to=YYYY-MM[&status=...]
+//
+// Для каждого месяца — операции каждого инвестора, суммы по типам и капитал
+// на начало и конец месяца. По умолчанию from — месяц первой операции,
+// to — текущий месяц; status — как у GET /api/investors (по умолчанию active).
+func (s *Server) handleMonthlyGrid(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+
+	ctx := r.Context()
+	ws := workspaceID(r)
+	q := r.URL.Query()
+
+	status := models.InvestorStatus(q.Get("status"))
+	if status == "" {
+		status = models.InvestorActive
+	}
+	if !status.Valid() {
+		writeJSON(w, 400, errorResponse{Error: "status must be active, archived or deleted"})
+		return
+	}
+
+	var from, to *time.Time
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &from}, {"to", &to}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse("2006-01", v)
+			if err != nil {
+				writeJSON(w, 400, errorResponse{Error: "invalid " + p.name + ", must be YYYY-MM"})
+				return
+			}
+			*p.dst = &t
+		}
+	}
+
+	investors, err := s.repo.ListInvestors(ctx, ws, status)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+	payouts, err := s.repo.GetPayouts(ctx, ws)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	if to == nil {
+		now := time.Now().UTC()
+		to = &now
+	}
+	if from == nil {
+		from = firstPayoutDate(payouts)
+		if from == nil || from.After(*to) {
+			from = to
+		}
+	}
+
+	months := (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+	switch {
+	case months <= 0:
+		writeJSON(w, 400, errorResponse{Error: "from must not be after to"})
+		return
+	case months > maxGridMonths:
+		writeJSON(w, 400, errorResponse{Error: "range is too long, at most 240 months"})
+		return
+	}
+
+	writeJSON(w, 200, ledger.BuildMonthlyGrid(investors, payouts, *from, *to))
+}
+
+// firstPayoutDate — дата самой ранней действующей операции (nil, если их нет).
+func firstPayoutDate(payouts []models.Payout) *time.Time {
+	var first *time.Time
+	for _, p := range payouts {
+		if p.Voided() || p.PeriodDate == nil {
+			continue
+		}
+		if first == nil || p.PeriodDate.Before(*first) {
+			first = p.PeriodDate
+		}
+	}
+	return first
+}
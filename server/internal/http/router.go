@@ -2,8 +2,12 @@ package http
 
 import (
 	"invest/internal/config"
+	"invest/internal/mailer"
+	"invest/internal/models"
 	"invest/internal/repository"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/rs/cors"
 )
@@ -12,13 +16,32 @@ type Server struct {
 	repo          *repository.Repository
 	jwtSecret     []byte
 	secretRegCode string
+
+	trustedProxies []*net.IPNet
+	authLimiter    *ipLimiter
+	refreshLimiter *ipLimiter
+
+	mailer mailer.Mailer
+	appURL string
 }
 
-func NewServer(repo *repository.Repository, cfg *config.Config) *Server {
+func NewServer(repo *repository.Repository, mail mailer.Mailer, cfg *config.Config) *Server {
 	return &Server{
 		repo:          repo,
 		jwtSecret:     []byte(cfg.JWTSecret),
 		secretRegCode: cfg.SecretRegCode,
+
+		mailer: mail,
+		appURL: cfg.AppURL,
+
+		trustedProxies: parseTrustedProxies(cfg.TrustedProxies),
+
+		// вход, регистрация, 2FA: 10 попыток сразу, дальше одна в 6 секунд с IP
+		authLimiter: newIPLimiter(10, 6*time.Second),
+
+		// refresh — отдельно: каждый клиент обновляет токен раз в 15 минут,
+		// и пользователи за одним NAT не должны отнимать друг у друга попытки входа
+		refreshLimiter: newIPLimiter(60, time.Second),
 	}
 }
 
@@ -30,27 +53,91 @@ func (s *Server) Routes() http.Handler {
 	//     AUTH (public)
 	// ============================
 	//
-	mux.HandleFunc("/api/login", s.handleLogin)
-	mux.HandleFunc("/api/register", s.handleRegister)
+	mux.HandleFunc("/api/login", s.withRateLimit(s.authLimiter, s.handleLogin))
+	mux.HandleFunc("/api/register", s.withRateLimit(s.authLimiter, s.handleRegister))
+	mux.HandleFunc("/api/login/2fa", s.withRateLimit(s.authLimiter, s.handleLoginTwoFactor))
+	mux.HandleFunc("/api/refresh", s.withRateLimit(s.refreshLimiter, s.handleRefresh))
+	mux.HandleFunc("/api/password/forgot", s.withRateLimit(s.authLimiter, s.handleForgotPassword))
+	mux.HandleFunc("/api/password/reset", s.withRateLimit(s.authLimiter, s.handleResetPassword))
+
+	// Права по ролям: viewer — только чтение, accountant — операции
+	// и инвесторы, owner — удаление инвесторов и управление пользователями.
+	viewer, accountant, owner := models.RoleViewer, models.RoleAccountant, models.RoleOwner
+
+	// выход доступен любому вошедшему пользователю
+	mux.HandleFunc("/api/logout", s.withAuth(s.withRole(viewer, viewer, s.handleLogout)))
+	mux.HandleFunc("/api/logout-all", s.withAuth(s.withRole(viewer, viewer, s.handleLogoutAll)))
+
+	// двухфакторная аутентификация текущего пользователя
+	mux.HandleFunc("/api/me/2fa", s.withAuth(s.withRole(viewer, viewer, s.handleMyTwoFactor)))
+	mux.HandleFunc("/api/me/2fa/", s.withAuth(s.withRole(viewer, viewer, s.handleMyTwoFactor)))
+	mux.HandleFunc("/api/me/password", s.withAuth(s.withRole(viewer, viewer, s.handleChangePassword)))
 
 	//
 	// ============================
 	//     INVESTORS (protected)
 	// ============================
 	//
-	mux.HandleFunc("/api/investors", s.withAuth(s.handleInvestors))
-	mux.HandleFunc("/api/investors/", s.withAuth(s.handleInvestorByID))
+	// создание инвестора проводит начальное вложение — тоже с Idempotency-Key
+	mux.HandleFunc("/api/investors", s.withAuth(s.withRole(viewer, accountant, s.withIdempotency(maxIdempotentBody, s.handleInvestors))))
+	mux.HandleFunc("/api/investors/", s.withAuth(s.withRole(viewer, accountant, s.handleInvestorByID)))
+
+	// удаление, архив, восстановление и purge — только владелец
+	mux.HandleFunc("DELETE /api/investors/{id}", s.withAuth(s.withRole(owner, owner, s.handleInvestorByID)))
+	mux.HandleFunc("POST /api/investors/{id}/archive", s.withAuth(s.withRole(owner, owner, s.handleInvestorByID)))
+	mux.HandleFunc("POST /api/investors/{id}/restore", s.withAuth(s.withRole(owner, owner, s.handleInvestorByID)))
+	mux.HandleFunc("POST /api/investors/{id}/purge", s.withAuth(s.withRole(owner, owner, s.handleInvestorByID)))
 
 	//
 	// ============================
 	//     PAYOUTS (protected)
 	// ============================
 	//
+	// POST-запросы, проводящие деньги, принимают заголовок Idempotency-Key
+	//
 	// ВАЖНО: сперва более длинный маршрут
-	mux.HandleFunc("/api/payouts/topup", s.withAuth(s.handleTopup))
+	mux.HandleFunc("/api/payouts/topup", s.withAuth(s.withRole(viewer, accountant, s.withIdempotency(maxIdempotentBody, s.handleTopup))))
 
 	// Затем общий обработчик выплат
-	mux.HandleFunc("/api/payouts", s.withAuth(s.handlePayouts))
+	mux.HandleFunc("/api/payouts", s.withAuth(s.withRole(viewer, accountant, s.withIdempotency(maxIdempotentBody, s.handlePayouts))))
+
+	// Сторно и исправление: /api/payouts/{id}/reverse|correct
+	mux.HandleFunc("/api/payouts/", s.withAuth(s.withRole(viewer, accountant, s.withIdempotency(maxIdempotentBody, s.handlePayoutByID))))
+
+	// Пакетные выплаты по всем инвесторам
+	mux.HandleFunc("/api/payout-runs", s.withAuth(s.withRole(viewer, accountant, s.withIdempotency(maxIdempotentBody, s.handlePayoutRuns))))
+	mux.HandleFunc("/api/payout-runs/", s.withAuth(s.withRole(viewer, accountant, s.withIdempotency(maxIdempotentBody, s.handlePayoutRunByID))))
+
+	// Распределение общей прибыли фонда пропорционально капиталу
+	mux.HandleFunc("/api/profit-distributions", s.withAuth(s.withRole(viewer, accountant, s.withIdempotency(maxIdempotentBody, s.handleProfitDistributions))))
+
+	//
+	// ============================
+	//     REPORTS (protected)
+	// ============================
+	//
+	// месяц × инвестор: общая основа для таблицы, Excel и PDF
+	mux.HandleFunc("/api/reports/monthly-grid", s.withAuth(s.withRole(viewer, viewer, s.handleMonthlyGrid)))
+
+	// выгрузки для скриптов и рассылок: ?from=&to=&investor_id=
+	mux.HandleFunc("/api/exports/investors.xlsx", s.withAuth(s.withRole(viewer, viewer, s.handleExportXLSX)))
+
+	// импорт CSV / XLSX: ?dry_run=true — только проверка
+	mux.HandleFunc("/api/imports", s.withAuth(s.withRole(accountant, accountant, s.withIdempotency(maxImportSize, s.handleImports))))
+
+	//
+	// ============================
+	//     USERS (owner)
+	// ============================
+	//
+	mux.HandleFunc("/api/users", s.withAuth(s.withRole(owner, owner, s.handleUsers)))
+	mux.HandleFunc("/api/users/", s.withAuth(s.withRole(owner, owner, s.handleUserByID)))
+	mux.HandleFunc("/api/invites", s.withAuth(s.withRole(owner, owner, s.handleInvites)))
+	mux.HandleFunc("/api/workspace/security", s.withAuth(s.withRole(owner, owner, s.handleWorkspaceSecurity)))
+
+	// журнал действий
+	mux.HandleFunc("/api/audit", s.withAuth(s.withRole(owner, owner, s.handleAudit)))
+	mux.HandleFunc("/api/audit/verify", s.withAuth(s.withRole(owner, owner, s.handleAuditVerify)))
 
 	//
 	// ============================
@@ -61,6 +148,7 @@ func (s *Server) Routes() http.Handler {
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"*"},
+		ExposedHeaders:   []string{"ETag", "X-Next-Cursor"},
 		AllowCredentials: true,
 	})
 
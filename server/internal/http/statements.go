@@ -0,0 +1,306 @@
+package http
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"invest/internal/ledger"
+	"invest/internal/models"
+	"invest/internal/pdf"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// ========================
+//     ВЫПИСКА ИНВЕСТОРА (PDF)
+// ========================
+//
+
+// handleInvestorStatement — GET /api/investors/{id}/statement.pdf?from=&to=
+//
+// Остаток на начало периода, все операции периода с капиталом после каждой,
+// остаток на конец и сводка по прибыли. Период — как у выгрузки XLSX:
+// по умолчанию с первой операции инвестора по сегодня.
+func (s *Server) handleInvestorStatement(w http.ResponseWriter, r *http.Request, id int64) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+
+	ctx := r.Context()
+	ws := workspaceID(r)
+
+	from, to, msg := parseDateRange(r.URL.Query())
+	if msg != "" {
+		writeJSON(w, 400, errorResponse{Error: msg})
+		return
+	}
+
+	inv, err := s.repo.GetInvestorByID(ctx, ws, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeJSON(w, 404, errorResponse{Error: "investor not found"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	payouts, err := s.repo.GetPayoutsByInvestor(ctx, ws, id)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	p := &exportParams{investors: []models.Investor{*inv}, payouts: payouts}
+	p.from, p.to = defaultDateRange(from, to, payouts)
+	if p.from.After(p.to) {
+		writeJSON(w, 400, errorResponse{Error: "from must not be after to"})
+		return
+	}
+
+	doc, err := pdf.New()
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+	writeStatement(doc, *inv, p)
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	name := fmt.Sprintf("statement_%d_%s_%s.pdf", inv.ID, p.from.Format("2006-01-02"), p.to.Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	_, _ = buf.WriteTo(w)
+}
+
+// оформление выписки
+const (
+	stMargin    = 40.0
+	stRowHeight = 18.0
+)
+
+var (
+	stGreen = pdf.Color{R: 34, G: 197, B: 94}
+	stBlue  = pdf.Color{R: 59, G: 130, B: 246}
+	stZebra = pdf.Color{R: 241, G: 245, B: 249}
+	stMuted = pdf.Color{R: 100, G: 116, B: 139}
+)
+
+// stColumn — колонка таблицы выписки.
+type stColumn struct {
+	title string
+	width float64
+	right bool // деньги выравниваются вправо
+}
+
+// statementWriter раскладывает выписку по страницам сверху вниз.
+type statementWriter struct {
+	doc *pdf.Document
+	y   float64 // верх следующего блока
+}
+
+// ensure начинает новую страницу, если блок высотой h не помещается.
+func (sw *statementWriter) ensure(h float64) bool {
+	if sw.y+h <= pdf.PageHeight-stMargin {
+		return false
+	}
+	sw.doc.AddPage()
+	sw.y = stMargin
+	return true
+}
+
+func (sw *statementWriter) heading(text string) {
+	sw.ensure(30 + 2*stRowHeight)
+	sw.doc.SetFont(pdf.Bold, 13)
+	sw.doc.SetTextColor(pdf.Black)
+	sw.doc.Text(stMargin, sw.y+14, text)
+	sw.y += 24
+}
+
+func (sw *statementWriter) header(cols []stColumn, fill pdf.Color) {
+	cells := make([]string, len(cols))
+	for i, c := range cols {
+		cells[i] = c.title
+	}
+	sw.doc.SetFillColor(fill)
+	sw.doc.FillRect(stMargin, sw.y, pdf.PageWidth-2*stMargin, stRowHeight)
+	sw.doc.SetFont(pdf.Bold, 10)
+	sw.doc.SetTextColor(pdf.White)
+	sw.cells(cols, cells)
+}
+
+// row пишет строку таблицы; при переносе на новую страницу повторяет шапку.
+func (sw *statementWriter) row(cols []stColumn, fill pdf.Color, cells []string, bold, zebra bool) {
+	if sw.ensure(stRowHeight) {
+		sw.header(cols, fill)
+	}
+	if zebra {
+		sw.doc.SetFillColor(stZebra)
+		sw.doc.FillRect(stMargin, sw.y, pdf.PageWidth-2*stMargin, stRowHeight)
+	}
+	style := pdf.Regular
+	if bold {
+		style = pdf.Bold
+	}
+	sw.doc.SetFont(style, 10)
+	sw.doc.SetTextColor(pdf.Black)
+	sw.cells(cols, cells)
+}
+
+func (sw *statementWriter) cells(cols []stColumn, cells []string) {
+	x := stMargin
+	baseline := sw.y + stRowHeight - 5.5
+	for i, c := range cols {
+		switch {
+		case cells[i] == "":
+		case c.right:
+			sw.doc.TextRight(x+c.width-6, baseline, cells[i])
+		default:
+			sw.doc.Text(x+6, baseline, cells[i])
+		}
+		x += c.width
+	}
+	sw.y += stRowHeight
+}
+
+// writeStatement рисует выписку инвестора за период p.from..p.to.
+func writeStatement(doc *pdf.Document, inv models.Investor, p *exportParams) {
+	doc.AddPage()
+	sw := &statementWriter{doc: doc, y: stMargin}
+
+	// ===== заголовок =====
+	doc.SetFont(pdf.Bold, 20)
+	doc.Text(stMargin, sw.y+20, "Выписка по инвестору")
+	sw.y += 34
+
+	name := inv.FullName
+	if name == "" {
+		name = "Без имени"
+	}
+	doc.SetFont(pdf.Bold, 14)
+	doc.Text(stMargin, sw.y+14, name)
+	sw.y += 24
+
+	doc.SetFont(pdf.Regular, 10)
+	doc.SetTextColor(stMuted)
+	info := []string{
+		fmt.Sprintf("Период: %s — %s", formatDate(p.from), formatDate(p.to)),
+		fmt.Sprintf("Инвестор № %d, в учёте с %s", inv.ID, formatDate(inv.CreatedAt)),
+	}
+	if inv.MonthlyPercent != nil {
+		info = append(info, "Ставка: "+strings.ReplaceAll(inv.MonthlyPercent.String(), ".", ",")+" % в месяц")
+	}
+	for _, line := range info {
+		doc.Text(stMargin, sw.y+10, line)
+		sw.y += 15
+	}
+	sw.y += 12
+
+	open := ledger.ComputeAt(inv, p.payouts, p.from.AddDate(0, 0, -1))
+	closing := ledger.ComputeAt(inv, p.payouts, p.to)
+
+	// ===== остатки =====
+	summary := []stColumn{{"Показатель", 315, false}, {"Сумма", 200.28, true}}
+	sw.heading("Движение капитала")
+	sw.header(summary, stGreen)
+	rows := [][2]string{
+		{"Капитал на начало периода", formatRub(open.CapitalNow)},
+		{"Изменение вложенной суммы", formatRub(closing.InvestedAmount.Sub(open.InvestedAmount))},
+		{"Реинвест", formatRub(closing.ReinvestedTotal.Sub(open.ReinvestedTotal))},
+		{"Пополнения", formatRub(closing.TopupsTotal.Sub(open.TopupsTotal))},
+		{"Снято капитала", formatRub(closing.WithdrawnCapital.Sub(open.WithdrawnCapital))},
+		{"Капитал на конец периода", formatRub(closing.CapitalNow)},
+	}
+	for i, r := range rows {
+		sw.row(summary, stGreen, r[:], i == 0 || i == len(rows)-1, i%2 == 1)
+	}
+	sw.y += 20
+
+	// ===== операции =====
+	ops := []stColumn{{"Дата", 80, false}, {"Операция", 175, false}, {"Сумма", 125, true}, {"Капитал после", 135.28, true}}
+	sw.heading("Операции")
+	sw.header(ops, stBlue)
+	sw.row(ops, stBlue, []string{formatDate(p.from), "Остаток на начало", "", formatRub(open.CapitalNow)}, true, false)
+
+	capital := open.CapitalNow
+	n := 0
+	for _, op := range p.payouts {
+		if op.InvestorID != inv.ID || op.Voided() || op.PeriodDate == nil {
+			continue
+		}
+		if op.PeriodDate.Before(p.from) || op.PeriodDate.After(p.to) {
+			continue
+		}
+
+		capital = capital.Add(ledger.CapitalDelta(op))
+		n++
+
+		amount := formatRub(op.PayoutAmount)
+		if op.PayoutAmount > 0 {
+			amount = "+" + amount
+		}
+		sw.row(ops, stBlue, []string{formatDate(*op.PeriodDate), op.Kind.Title(), amount, formatRub(capital)}, false, n%2 == 1)
+	}
+	if n == 0 {
+		sw.row(ops, stBlue, []string{"", "Операций за период нет", "", ""}, false, true)
+	}
+	sw.row(ops, stBlue, []string{formatDate(p.to), "Остаток на конец", "", formatRub(closing.CapitalNow)}, true, false)
+	sw.y += 20
+
+	// ===== прибыль =====
+	sw.heading("Прибыль")
+	sw.header(summary, stGreen)
+	profitPeriod := closing.TotalProfitAllTime.Sub(open.TotalProfitAllTime)
+	rows = [][2]string{
+		{"Начислено за период", formatRub(profitPeriod)},
+		{"Снято прибыли за период", formatRub(closing.WithdrawnProfit.Sub(open.WithdrawnProfit))},
+		{"Прибыль за всё время", formatRub(closing.TotalProfitAllTime)},
+		{"Снято прибыли за всё время", formatRub(closing.WithdrawnProfit)},
+		{"Чистая прибыль на конец периода", formatRub(closing.NetProfit)},
+	}
+	for i, r := range rows {
+		sw.row(summary, stGreen, r[:], i == len(rows)-1, i%2 == 1)
+	}
+
+	// ===== номера страниц =====
+	// рисуются в конце, когда известно общее число страниц
+	doc.SetFont(pdf.Regular, 8)
+	doc.SetTextColor(stMuted)
+	total := doc.PageCount()
+	for i := 1; i <= total; i++ {
+		doc.SetPage(i)
+		doc.TextRight(pdf.PageWidth-stMargin, pdf.PageHeight-20, fmt.Sprintf("Стр. %d из %d", i, total))
+	}
+}
+
+func formatDate(t time.Time) string { return t.Format("02.01.2006") }
+
+// formatRub — сумма по-русски: 1 234 567,89 руб.
+// Знака ₽ во встроенном шрифте нет.
+func formatRub(m models.Money) string {
+	s := m.String()
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "−", s[1:]
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+
+	var b strings.Builder
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(c)
+	}
+	return sign + b.String() + "," + frac + " руб."
+}
@@ -0,0 +1,399 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"invest/internal/models"
+	"invest/internal/repository"
+	"invest/internal/totp"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+//
+// ========================
+//      TWO-FACTOR (TOTP)
+// ========================
+//
+
+const (
+	totpIssuer        = "Invest"
+	mfaTokenTTL       = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+// mfaClaims — промежуточный токен между паролем и вторым фактором.
+// В withAuth он не проходит: в нём нет пространства и сессии.
+type mfaClaims struct {
+	UserID  int64  `json:"sub"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+func (s *Server) issueMFAToken(u *models.User) (string, error) {
+	claims := mfaClaims{
+		UserID:  u.ID,
+		Purpose: "mfa",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(mfaTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
+}
+
+func (s *Server) parseMFAToken(raw string) (int64, bool) {
+	t, err := jwt.ParseWithClaims(raw, &mfaClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return s.jwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !t.Valid {
+		return 0, false
+	}
+	claims, ok := t.Claims.(*mfaClaims)
+	if !ok || claims.Purpose != "mfa" || claims.UserID == 0 {
+		return 0, false
+	}
+	return claims.UserID, true
+}
+
+// handleLoginTwoFactor — POST /api/login/2fa {mfaToken, code}: второй шаг входа.
+// code — текущий TOTP-код или один из кодов восстановления.
+func (s *Server) handleLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+
+	var req struct {
+		MFAToken string `json:"mfaToken"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, 400, errorResponse{Error: "invalid json"})
+		return
+	}
+
+	id, ok := s.parseMFAToken(req.MFAToken)
+	if !ok {
+		writeJSON(w, 401, errorResponse{Error: "invalid or expired mfa token"})
+		return
+	}
+
+	u, err := s.repo.GetUserByID(r.Context(), id)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+	if u == nil || !u.TOTPEnabled {
+		writeJSON(w, 401, errorResponse{Error: "invalid or expired mfa token"})
+		return
+	}
+	if checkLocked(w, u) {
+		return
+	}
+
+	ok, err = s.verifySecondFactor(r.Context(), u.ID, req.Code)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+	if !ok {
+		s.loginFailed(r.Context(), r, u)
+		writeJSON(w, 401, errorResponse{Error: "invalid code"})
+		return
+	}
+	s.loginSucceeded(r.Context(), u)
+
+	resp, err := s.startSession(r.Context(), u)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: "token error"})
+		return
+	}
+	writeJSON(w, 200, resp)
+}
+
+// verifySecondFactor принимает TOTP-код (каждый — один раз) или код восстановления.
+func (s *Server) verifySecondFactor(ctx context.Context, userID int64, code string) (bool, error) {
+	return verifyCode(ctx, s.repo, userID, code, time.Now())
+}
+
+// secondFactorStore — часть репозитория, нужная для проверки второго фактора.
+// Повтор кодов отсекает именно она: UseTOTPStep и UseRecoveryCode
+// принимают каждый шаг и каждый код восстановления только один раз.
+type secondFactorStore interface {
+	GetTwoFactor(ctx context.Context, userID int64) (*models.TwoFactor, error)
+	UseTOTPStep(ctx context.Context, userID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int64, hash string) (bool, error)
+}
+
+func verifyCode(ctx context.Context, store secondFactorStore, userID int64, code string, now time.Time) (bool, error) {
+	tf, err := store.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if !tf.Enabled || strings.TrimSpace(code) == "" {
+		return false, nil
+	}
+
+	if step, ok := totp.Validate(tf.Secret, code, now); ok {
+		return store.UseTOTPStep(ctx, userID, step)
+	}
+	return store.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+}
+
+// handleMyTwoFactor — /api/me/2fa[/setup|/enable|/disable|/recovery-codes].
+func (s *Server) handleMyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	uid := userID(r)
+
+	action := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/me/2fa"), "/")
+
+	if action == "" {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(405)
+			return
+		}
+		tf, err := s.repo.GetTwoFactor(ctx, uid)
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, 200, tf)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if action != "setup" {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, 400, errorResponse{Error: "invalid json"})
+			return
+		}
+	}
+
+	switch action {
+
+	// секрет и otpauth:// ссылка для QR; 2FA включится после подтверждения кодом
+	case "setup":
+		u, err := s.repo.GetUserByID(ctx, uid)
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			return
+		}
+		if u == nil {
+			writeJSON(w, 404, errorResponse{Error: "user not found"})
+			return
+		}
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: "secret error"})
+			return
+		}
+
+		err = s.repo.SetPendingTOTPSecret(ctx, uid, secret)
+		if errors.Is(err, repository.ErrTwoFactorState) {
+			writeJSON(w, 409, errorResponse{Error: "two-factor authentication already enabled"})
+			return
+		}
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			return
+		}
+
+		writeJSON(w, 200, map[string]any{
+			"secret": secret,
+			"uri":    totp.ProvisioningURI(totpIssuer, u.Email, secret),
+		})
+
+	// первый код из приложения подтверждает секрет; выдаются коды восстановления
+	// и новый access-токен (без ограничения "только настройка 2FA")
+	case "enable":
+		tf, err := s.repo.GetTwoFactor(ctx, uid)
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			return
+		}
+		if tf.Enabled || tf.PendingSecret == "" {
+			writeJSON(w, 409, errorResponse{Error: "call /api/me/2fa/setup first"})
+			return
+		}
+
+		step, ok := totp.Validate(tf.PendingSecret, req.Code, time.Now())
+		if !ok {
+			writeJSON(w, 400, errorResponse{Error: "invalid code"})
+			return
+		}
+
+		codes, hashes, err := newRecoveryCodes()
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: "recovery codes error"})
+			return
+		}
+
+		err = s.repo.InTx(ctx, func(ctx context.Context) error {
+			if err := s.repo.EnableTOTP(ctx, uid, step, hashes); err != nil {
+				return err
+			}
+			return s.audit(ctx, r, "user.2fa_enable", "user", uid, nil, nil)
+		})
+		if errors.Is(err, repository.ErrTwoFactorState) {
+			writeJSON(w, 409, errorResponse{Error: "call /api/me/2fa/setup first"})
+			return
+		}
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			return
+		}
+
+		u, err := s.repo.GetUserByID(ctx, uid)
+		if err != nil || u == nil {
+			writeJSON(w, 500, errorResponse{Error: "user lookup failed"})
+			return
+		}
+		token, err := s.issueToken(u, sessionID(r))
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: "token error"})
+			return
+		}
+
+		writeJSON(w, 200, map[string]any{
+			"recoveryCodes": codes,
+			"token":         token,
+		})
+
+	case "disable":
+		tf, err := s.repo.GetTwoFactor(ctx, uid)
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			return
+		}
+		if tf.Required {
+			writeJSON(w, 409, errorResponse{Error: "two-factor authentication is required by the workspace"})
+			return
+		}
+		if !s.checkSecondFactor(w, r, req.Code) {
+			return
+		}
+
+		err = s.repo.InTx(ctx, func(ctx context.Context) error {
+			if err := s.repo.DisableTOTP(ctx, uid); err != nil {
+				return err
+			}
+			return s.audit(ctx, r, "user.2fa_disable", "user", uid, nil, nil)
+		})
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			return
+		}
+		w.WriteHeader(204)
+
+	case "recovery-codes":
+		if !s.checkSecondFactor(w, r, req.Code) {
+			return
+		}
+
+		codes, hashes, err := newRecoveryCodes()
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: "recovery codes error"})
+			return
+		}
+		err = s.repo.InTx(ctx, func(ctx context.Context) error {
+			if err := s.repo.ReplaceRecoveryCodes(ctx, uid, hashes); err != nil {
+				return err
+			}
+			return s.audit(ctx, r, "user.recovery_codes", "user", uid, nil, nil)
+		})
+		if err != nil {
+			writeJSON(w, 500, errorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, 200, map[string]any{"recoveryCodes": codes})
+
+	default:
+		writeJSON(w, 404, errorResponse{Error: "not found"})
+	}
+}
+
+// checkSecondFactor — действия с уже включённой 2FA подтверждаются кодом.
+func (s *Server) checkSecondFactor(w http.ResponseWriter, r *http.Request, code string) bool {
+	ok, err := s.verifySecondFactor(r.Context(), userID(r), code)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return false
+	}
+	if !ok {
+		writeJSON(w, 400, errorResponse{Error: "invalid code"})
+		return false
+	}
+	return true
+}
+
+// handleWorkspaceSecurity — PUT /api/workspace/security {require2fa} (owner).
+// Пользователи без 2FA после этого могут только настроить её.
+func (s *Server) handleWorkspaceSecurity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(405)
+		return
+	}
+
+	var req struct {
+		Require2FA *bool `json:"require2fa"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, 400, errorResponse{Error: "invalid json"})
+		return
+	}
+	if req.Require2FA == nil {
+		writeJSON(w, 400, errorResponse{Error: "require2fa required"})
+		return
+	}
+
+	err := s.repo.InTx(r.Context(), func(ctx context.Context) error {
+		if err := s.repo.SetWorkspaceRequire2FA(ctx, workspaceID(r), *req.Require2FA); err != nil {
+			return err
+		}
+		return s.audit(ctx, r, "workspace.security", "workspace", workspaceID(r), nil,
+			map[string]any{"require2fa": *req.Require2FA})
+	})
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, 200, map[string]any{"require2fa": *req.Require2FA})
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes — коды вида "abcde-fghij" и их хеши для хранения.
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 6)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashRecoveryCode(raw))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode — регистр, пробелы и дефис при вводе не важны.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return hashToken(code)
+}
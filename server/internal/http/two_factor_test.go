@@ -0,0 +1,99 @@
+package http
+
+import (
+	"context"
+	"invest/internal/models"
+	"invest/internal/totp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryTwoFactor повторяет условия UPDATE из repository: шаг принимается,
+// только если он больше последнего, код восстановления — пока не погашен.
+type memoryTwoFactor struct {
+	tf       models.TwoFactor
+	lastStep int64
+	recovery map[string]bool // хеш → использован
+}
+
+func (m *memoryTwoFactor) GetTwoFactor(context.Context, int64) (*models.TwoFactor, error) {
+	tf := m.tf
+	return &tf, nil
+}
+
+func (m *memoryTwoFactor) UseTOTPStep(_ context.Context, _ int64, step int64) (bool, error) {
+	if step <= m.lastStep {
+		return false, nil
+	}
+	m.lastStep = step
+	return true, nil
+}
+
+func (m *memoryTwoFactor) UseRecoveryCode(_ context.Context, _ int64, hash string) (bool, error) {
+	used, ok := m.recovery[hash]
+	if !ok || used {
+		return false, nil
+	}
+	m.recovery[hash] = true
+	return true, nil
+}
+
+func TestVerifyCode(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := &memoryTwoFactor{
+		tf:       models.TwoFactor{Enabled: true, Secret: secret},
+		recovery: make(map[string]bool),
+	}
+	for _, h := range hashes {
+		store.recovery[h] = false
+	}
+
+	now := time.Now()
+	later := now.Add(totp.Period * time.Second)
+	current, _ := totp.CodeAt(secret, totp.Step(now))
+	next, _ := totp.CodeAt(secret, totp.Step(later))
+
+	// шаги выполняются по порядку над одним хранилищем
+	steps := []struct {
+		name string
+		code string
+		at   time.Time
+		want bool
+	}{
+		{name: "totp code", code: current, at: now, want: true},
+		{name: "same totp code again", code: current, at: now},
+		{name: "same totp code in the next step", code: current, at: later},
+		{name: "next totp code", code: next, at: later, want: true},
+
+		{name: "recovery code", code: codes[0], at: now, want: true},
+		{name: "same recovery code again", code: codes[0], at: now},
+		{name: "same recovery code in another format", code: strings.ToUpper(strings.ReplaceAll(codes[0], "-", " ")), at: now},
+		{name: "another recovery code, any format", code: " " + strings.ToUpper(codes[1]) + " ", at: now, want: true},
+		{name: "unknown code", code: "zzzzz-zzzzz", at: now},
+		{name: "empty code", code: "  ", at: now},
+	}
+
+	for _, s := range steps {
+		got, err := verifyCode(context.Background(), store, 1, s.code, s.at)
+		if err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if got != s.want {
+			t.Errorf("%s: verifyCode() = %v, want %v", s.name, got, s.want)
+		}
+	}
+
+	store.tf.Enabled = false
+	if ok, _ := verifyCode(context.Background(), store, 1, codes[2], now); ok {
+		t.Error("verifyCode() with 2FA disabled = true, want false")
+	}
+}
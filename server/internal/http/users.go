@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"invest/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//
+// ========================
+//      USERS (owner)
+// ========================
+//
+
+// handleUsers — GET /api/users: пользователи рабочего пространства.
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+
+	list, err := s.repo.ListUsers(r.Context(), workspaceID(r))
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, 200, list)
+}
+
+// handleUserByID — PUT /api/users/{id}: смена роли.
+func (s *Server) handleUserByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(405)
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/users/"), 10, 64)
+	if err != nil {
+		writeJSON(w, 400, errorResponse{Error: "invalid user id"})
+		return
+	}
+
+	var req struct {
+		Role models.Role `json:"role"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, 400, errorResponse{Error: "invalid json"})
+		return
+	}
+	if !req.Role.Valid() {
+		writeJSON(w, 400, errorResponse{Error: "role must be owner, accountant or viewer"})
+		return
+	}
+
+	// свою роль менять нельзя — иначе пространство может остаться без владельца
+	if id == userID(r) {
+		writeJSON(w, 400, errorResponse{Error: "cannot change your own role"})
+		return
+	}
+
+	// прежняя роль — для журнала; пользователь другого пространства = не найден
+	before, err := s.repo.GetUserByID(r.Context(), id)
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+	if before == nil || before.WorkspaceID != workspaceID(r) {
+		writeJSON(w, 404, errorResponse{Error: "user not found"})
+		return
+	}
+
+	err = s.repo.InTx(r.Context(), func(ctx context.Context) error {
+		if err := s.repo.SetUserRole(ctx, workspaceID(r), id, req.Role); err != nil {
+			return err
+		}
+		return s.audit(ctx, r, "user.role", "user", id,
+			map[string]any{"role": before.Role}, map[string]any{"role": req.Role})
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		writeJSON(w, 404, errorResponse{Error: "user not found"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, 500, errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, 200, map[string]any{"id": id, "role": req.Role})
+}
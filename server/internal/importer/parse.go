@@ -0,0 +1,442 @@
+// Package importer разбирает CSV и XLSX со списком операций инвесторов
+// и готовит их к проведению: сопоставляет колонки с полями, находит
+// (или создаёт) инвесторов и проверяет остатки так же, как при ручном вводе.
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"invest/internal/models"
+	"invest/internal/xlsx"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Field — поле операции, которое берётся из колонки файла.
+type Field string
+
+const (
+	FieldInvestorID Field = "investor_id"
+	FieldInvestor   Field = "investor" // ФИО
+	FieldDate       Field = "date"
+	FieldKind       Field = "kind"
+	FieldAmount     Field = "amount"
+)
+
+var fields = []Field{FieldInvestorID, FieldInvestor, FieldDate, FieldKind, FieldAmount}
+
+// Mapping — поле → заголовок колонки. Поля, которых нет в Mapping,
+// ищутся по стандартным заголовкам (см. headerAliases).
+type Mapping map[Field]string
+
+// Validate проверяет, что в Mapping только известные поля.
+func (m Mapping) Validate() error {
+	for f := range m {
+		known := false
+		for _, k := range fields {
+			known = known || f == k
+		}
+		if !known {
+			return fmt.Errorf("unknown mapping field %q", f)
+		}
+	}
+	return nil
+}
+
+// headerAliases — заголовки по умолчанию (в нижнем регистре). Включают
+// заголовки листов-выписок из GET /api/exports/investors.xlsx.
+var headerAliases = map[Field][]string{
+	FieldInvestorID: {"investor_id", "id инвестора"},
+	FieldInvestor:   {"investor", "full_name", "фио", "инвестор"},
+	FieldDate:       {"date", "period_date", "дата"},
+	FieldKind:       {"kind", "type", "операция", "тип", "тип операции"},
+	FieldAmount:     {"amount", "payout_amount", "сумма"},
+}
+
+// kindAliases — названия типов операций (в нижнем регистре) помимо кодов
+// и models.PayoutKind.Title().
+var kindAliases = map[string]models.PayoutKind{
+	"реинвестирование": models.KindReinvest,
+	"пополнение":       models.KindTopup,
+	"выплата прибыли":  models.KindProfitWithdrawal,
+	"вложение":         models.KindDeposit,
+	"корректировка":    models.KindAdjustment,
+}
+
+// служебные строки выписки из выгрузки XLSX
+const (
+	openingTitle = "остаток на начало"
+	totalTitle   = "итого"
+)
+
+// Row — операция из строки файла.
+type Row struct {
+	Sheet string // пусто для CSV
+	Line  int    // номер строки в файле, с 1
+
+	InvestorID   int64  // 0 — ищется по InvestorName
+	InvestorName string // может быть пустым, если задан InvestorID
+	Date         time.Time
+	Kind         models.PayoutKind
+	Amount       models.Money // со знаком, как хранится в payouts
+}
+
+// RowError — ошибка в конкретной строке файла.
+type RowError struct {
+	Sheet  string `json:"sheet,omitempty"`
+	Row    int    `json:"row"`
+	Column string `json:"column,omitempty"`
+	Error  string `json:"error"`
+}
+
+// ErrNoTable — в файле не нашлось ни одной таблицы с операциями.
+var ErrNoTable = errors.New("no table with date, kind and amount columns found")
+
+// сколько строк сверху просматривается в поисках заголовка
+const headerScanRows = 10
+
+// Parse читает CSV или XLSX (определяется по содержимому) и разбирает строки
+// с операциями. Ошибки отдельных строк возвращаются в RowError; error —
+// только если файл целиком не читается.
+func Parse(data []byte, m Mapping) ([]Row, []RowError, error) {
+	var sheets []xlsx.SheetData
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		var err error
+		if sheets, err = xlsx.Read(bytes.NewReader(data), int64(len(data))); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		rows, err := readCSV(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		sheets = []xlsx.SheetData{{Rows: rows}}
+	}
+
+	names := exportedNames(sheets)
+
+	var (
+		rows   []Row
+		errs   []RowError
+		tables int
+	)
+	for _, sh := range sheets {
+		t, ok := findTable(sh, m)
+		if !ok {
+			continue
+		}
+		tables++
+
+		// лист без колонки инвестора — выписка из нашей выгрузки:
+		// инвестор определяется по имени листа «<ID> <ФИО>»
+		sheetInvestor := ""
+		if t.cols[FieldInvestor] == 0 && t.cols[FieldInvestorID] == 0 {
+			if sheetInvestor = investorFromSheet(sh.Name, names); sheetInvestor == "" {
+				return nil, nil, errors.New("no investor column found")
+			}
+		}
+
+		for i := t.header + 1; i < len(sh.Rows); i++ {
+			row, rowErr, skip := t.parseRow(sh, i, sheetInvestor)
+			switch {
+			case skip:
+			case rowErr != nil:
+				errs = append(errs, *rowErr)
+			default:
+				rows = append(rows, row)
+			}
+		}
+	}
+	if tables == 0 {
+		return nil, nil, ErrNoTable
+	}
+	return rows, errs, nil
+}
+
+// readCSV читает CSV в UTF-8 (с BOM или без); разделитель — «;», «,»
+// или табуляция, смотря что встречается в первой строке.
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	if !utf8.Valid(data) {
+		return nil, errors.New("csv must be in UTF-8")
+	}
+
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+	comma := ','
+	best := bytes.Count(first, []byte(","))
+	for _, c := range []rune{';', '\t'} {
+		if n := bytes.Count(first, []byte(string(c))); n > best {
+			comma, best = c, n
+		}
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	return rows, nil
+}
+
+// table — найденная на листе таблица: строка заголовка и колонки полей (с 1).
+type table struct {
+	header int
+	cols   map[Field]int
+}
+
+// findTable ищет строку заголовка, в которой есть дата, тип и сумма.
+func findTable(sh xlsx.SheetData, m Mapping) (table, bool) {
+	for i := 0; i < len(sh.Rows) && i < headerScanRows; i++ {
+		t := table{header: i, cols: make(map[Field]int)}
+		for j, cell := range sh.Rows[i] {
+			title := normalize(cell)
+			if title == "" {
+				continue
+			}
+			for _, f := range fields {
+				if t.cols[f] == 0 && matchesHeader(f, title, m) {
+					t.cols[f] = j + 1
+				}
+			}
+		}
+		if t.cols[FieldDate] != 0 && t.cols[FieldKind] != 0 && t.cols[FieldAmount] != 0 {
+			return t, true
+		}
+	}
+	return table{}, false
+}
+
+func matchesHeader(f Field, title string, m Mapping) bool {
+	if h, ok := m[f]; ok {
+		return normalize(h) == title
+	}
+	for _, alias := range headerAliases[f] {
+		if alias == title {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRow разбирает строку i листа. skip — пустая или служебная строка.
+func (t table) parseRow(sh xlsx.SheetData, i int, sheetInvestor string) (row Row, rowErr *RowError, skip bool) {
+	cells := sh.Rows[i]
+	cell := func(f Field) string {
+		c := t.cols[f]
+		if c == 0 || c > len(cells) {
+			return ""
+		}
+		return strings.TrimSpace(cells[c-1])
+	}
+	fail := func(f Field, format string, args ...any) (Row, *RowError, bool) {
+		return Row{}, &RowError{Sheet: sh.Name, Row: i + 1, Column: string(f), Error: fmt.Sprintf(format, args...)}, false
+	}
+
+	empty := true
+	for _, c := range cells {
+		empty = empty && strings.TrimSpace(c) == ""
+	}
+	if empty {
+		return Row{}, nil, true
+	}
+
+	switch normalize(cell(FieldKind)) {
+	case totalTitle:
+		return Row{}, nil, true
+	case openingTitle:
+		// остаток на начало периода нельзя разложить на операции
+		for j, c := range cells {
+			if j+1 == t.cols[FieldDate] || j+1 == t.cols[FieldKind] {
+				continue
+			}
+			if m, err := parseAmount(c); err == nil && !m.IsZero() {
+				return fail(FieldKind, "non-zero opening balance can't be imported: export the whole period")
+			}
+		}
+		return Row{}, nil, true
+	}
+
+	row = Row{Sheet: sh.Name, Line: i + 1, InvestorName: sheetInvestor}
+
+	if v := cell(FieldInvestorID); v != "" && sheetInvestor == "" {
+		id, err := strconv.ParseInt(strings.TrimSuffix(v, ".0"), 10, 64)
+		if err != nil || id <= 0 {
+			return fail(FieldInvestorID, "invalid investor id %q", v)
+		}
+		row.InvestorID = id
+	}
+	if v := cell(FieldInvestor); v != "" && sheetInvestor == "" {
+		row.InvestorName = strings.Join(strings.Fields(v), " ")
+	}
+	if row.InvestorID == 0 && row.InvestorName == "" {
+		return fail(FieldInvestor, "investor is not set")
+	}
+
+	date, err := parseDate(cell(FieldDate))
+	if err != nil {
+		return fail(FieldDate, "%v", err)
+	}
+	row.Date = date
+
+	kind, ok := parseKind(cell(FieldKind))
+	if !ok {
+		return fail(FieldKind, "unknown operation kind %q", cell(FieldKind))
+	}
+	row.Kind = kind
+
+	amount, err := parseAmount(cell(FieldAmount))
+	if err != nil {
+		return fail(FieldAmount, "%v", err)
+	}
+	if amount, err = signedAmount(kind, amount); err != nil {
+		return fail(FieldAmount, "%v", err)
+	}
+	row.Amount = amount
+
+	return row, nil, false
+}
+
+// exportedNames — ID → ФИО с листа-сводки нашей выгрузки (колонки «ID» и «ФИО»
+// без колонки даты): имена листов обрезаны до 31 символа, а в сводке — полные.
+func exportedNames(sheets []xlsx.SheetData) map[int64]string {
+	names := make(map[int64]string)
+	for _, sh := range sheets {
+		if len(sh.Rows) == 0 {
+			continue
+		}
+		idCol, nameCol := 0, 0
+		for j, cell := range sh.Rows[0] {
+			switch normalize(cell) {
+			case "id":
+				idCol = j + 1
+			case "фио":
+				nameCol = j + 1
+			case "дата":
+				idCol = -1
+			}
+		}
+		if idCol <= 0 || nameCol == 0 {
+			continue
+		}
+		for _, row := range sh.Rows[1:] {
+			if len(row) < max(idCol, nameCol) {
+				continue
+			}
+			id, err := strconv.ParseInt(strings.TrimSpace(row[idCol-1]), 10, 64)
+			if err == nil && strings.TrimSpace(row[nameCol-1]) != "" {
+				names[id] = strings.Join(strings.Fields(row[nameCol-1]), " ")
+			}
+		}
+	}
+	return names
+}
+
+var sheetNameRe = regexp.MustCompile(`^(\d+)\s+(.+?)(?:\s+\(\d+\))?$`)
+
+// investorFromSheet — ФИО по имени листа-выписки «<ID> <ФИО>»;
+// пусто, если лист назван иначе.
+func investorFromSheet(sheet string, names map[int64]string) string {
+	m := sheetNameRe.FindStringSubmatch(strings.TrimSpace(sheet))
+	if m == nil {
+		return ""
+	}
+	if id, err := strconv.ParseInt(m[1], 10, 64); err == nil && names[id] != "" {
+		return names[id]
+	}
+	return m[2]
+}
+
+// ========================
+//    ЗНАЧЕНИЯ ЯЧЕЕК
+// ========================
+
+// normalize — для сравнения заголовков, типов и ФИО: нижний регистр,
+// ё → е, одиночные пробелы.
+func normalize(s string) string {
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	return strings.ReplaceAll(s, "ё", "е")
+}
+
+// parseDate принимает YYYY-MM-DD, DD.MM.YYYY и серийный номер даты Excel.
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("date is not set")
+	}
+	for _, layout := range []string{"2006-01-02", "02.01.2006", "2.1.2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	// 1 — 01.01.1900, 2958465 — 31.12.9999
+	if n, err := strconv.ParseFloat(s, 64); err == nil && n >= 1 && n < 2958466 {
+		return xlsx.DateFromSerial(n), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or DD.MM.YYYY", s)
+}
+
+func parseKind(s string) (models.PayoutKind, bool) {
+	title := normalize(s)
+	if k := models.PayoutKind(title); k.Valid() || k == models.KindDeposit || k == models.KindAdjustment {
+		return k, true
+	}
+	for _, k := range []models.PayoutKind{
+		models.KindReinvest, models.KindTopup, models.KindProfitWithdrawal,
+		models.KindCapitalWithdrawal, models.KindDeposit, models.KindAdjustment,
+	} {
+		if normalize(k.Title()) == title {
+			return k, true
+		}
+	}
+	k, ok := kindAliases[title]
+	return k, ok
+}
+
+var amountReplacer = strings.NewReplacer(
+	" ", "", "\u00a0", "", "\u202f", "", "\u2009", "",
+	"руб.", "", "руб", "", "₽", "",
+	"−", "-", ",", ".",
+)
+
+// parseAmount принимает «1234.56», «1 234,56», «-1 234,56 руб.».
+func parseAmount(s string) (models.Money, error) {
+	if s == "" {
+		return 0, errors.New("amount is not set")
+	}
+	v := amountReplacer.Replace(strings.ToLower(s))
+	// NaN, Inf и экспоненту в суммах не ждём
+	if strings.ContainsAny(v, "eEnN") {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	m, err := models.ParseMoney(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return m, nil
+}
+
+// signedAmount приводит сумму к знаку, с которым операция хранится:
+// снятия — отрицательные (знак в файле не важен), изменение вложения —
+// со знаком из файла, остальные — положительные.
+func signedAmount(kind models.PayoutKind, m models.Money) (models.Money, error) {
+	if m.IsZero() {
+		return 0, errors.New("amount must not be zero")
+	}
+	switch kind {
+	case models.KindProfitWithdrawal, models.KindCapitalWithdrawal:
+		return m.Abs().Neg(), nil
+	case models.KindAdjustment:
+		return m, nil
+	}
+	if m < 0 {
+		return 0, fmt.Errorf("amount of %s must be positive", kind)
+	}
+	return m, nil
+}
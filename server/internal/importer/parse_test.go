@@ -0,0 +1,285 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"invest/internal/models"
+	"invest/internal/xlsx"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseCSV(t *testing.T) {
+	data := "\ufeffФИО;Дата;Операция;Сумма\n" +
+		"Иванов  Иван;2024-01-15;Начальное вложение;1 000 000,00\n" +
+		"Иванов Иван;01.02.2024;reinvest;5000.5\n" +
+		";;;\n" +
+		"Петров Пётр;2.3.2024;Снятие капитала;10 000 руб.\n" +
+		"Петров Пётр;2024-03-05;снятие прибыли;−1 500,25 ₽\n" +
+		";2024-03-05;topup;100\n" +
+		"Петров Пётр;2024-13-01;topup;100\n" +
+		"Петров Пётр;2024-03-05;бонус;100\n" +
+		"Петров Пётр;2024-03-05;topup;-100\n" +
+		"Петров Пётр;2024-03-05;topup;1e3\n" +
+		"Петров Пётр;2024-03-05;topup;0\n"
+
+	rows, errs, err := Parse([]byte(data), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantRows := []Row{
+		{Line: 2, InvestorName: "Иванов Иван", Date: day("2024-01-15"), Kind: models.KindDeposit, Amount: 100000000},
+		{Line: 3, InvestorName: "Иванов Иван", Date: day("2024-02-01"), Kind: models.KindReinvest, Amount: 500050},
+		{Line: 5, InvestorName: "Петров Пётр", Date: day("2024-03-02"), Kind: models.KindCapitalWithdrawal, Amount: -1000000},
+		{Line: 6, InvestorName: "Петров Пётр", Date: day("2024-03-05"), Kind: models.KindProfitWithdrawal, Amount: -150025},
+	}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("rows = %+v\nwant %+v", rows, wantRows)
+	}
+
+	wantErrs := []struct {
+		row    int
+		column Field
+	}{
+		{7, FieldInvestor},
+		{8, FieldDate},
+		{9, FieldKind},
+		{10, FieldAmount},
+		{11, FieldAmount},
+		{12, FieldAmount},
+	}
+	if len(errs) != len(wantErrs) {
+		t.Fatalf("errors = %+v, want %d", errs, len(wantErrs))
+	}
+	for i, w := range wantErrs {
+		if errs[i].Row != w.row || errs[i].Column != string(w.column) || errs[i].Error == "" {
+			t.Errorf("error %d = %+v, want row %d, column %s", i, errs[i], w.row, w.column)
+		}
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	data := "Номер,Клиент,Дата платежа,Вид,Итого к выплате\n" +
+		"7,Иванов Иван,2024-01-31,reinvest,\"1,5\"\n"
+
+	if _, _, err := Parse([]byte(data), nil); !errors.Is(err, ErrNoTable) {
+		t.Fatalf("Parse() without mapping = %v, want ErrNoTable", err)
+	}
+
+	m := Mapping{
+		FieldInvestorID: "номер",
+		FieldInvestor:   "Клиент",
+		FieldDate:       "Дата платежа",
+		FieldKind:       "Вид",
+		FieldAmount:     "Итого к выплате",
+	}
+	if err := m.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	rows, errs, err := Parse([]byte(data), m)
+	if err != nil || len(errs) != 0 {
+		t.Fatalf("Parse() = %v, %+v", err, errs)
+	}
+	want := []Row{{Line: 2, InvestorID: 7, InvestorName: "Иванов Иван", Date: day("2024-01-31"), Kind: models.KindReinvest, Amount: 150}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %+v\nwant %+v", rows, want)
+	}
+
+	if err := (Mapping{"email": "E-mail"}).Validate(); err == nil {
+		t.Error("Validate() with unknown field: want error")
+	}
+}
+
+// exportBook — книга в формате GET /api/exports/investors.xlsx: сводка
+// с полными ФИО и лист-выписка на инвестора без колонки ФИО.
+func exportBook(t *testing.T, opening string) []byte {
+	t.Helper()
+
+	wb := xlsx.New()
+	sum := wb.AddSheet("Сводка")
+	sum.AddRow(xlsx.Header("ID"), xlsx.Header("ФИО"), xlsx.Header("Капитал"))
+	sum.AddRow(xlsx.Int(12), xlsx.Text("Константинопольский Константин Константинович"), xlsx.Number("1000", xlsx.StyleMoney))
+
+	st := wb.AddSheet("12 Константинопольский Константин Константинович")
+	st.AddRow(xlsx.Text("Константинопольский Константин Константинович"))
+	st.AddRow()
+	st.AddRow(xlsx.Header("Дата"), xlsx.Header("Операция"), xlsx.Header("Сумма"), xlsx.Header("Капитал"))
+	st.AddRow(xlsx.Empty(), xlsx.Text("Остаток на начало"), xlsx.Empty(), xlsx.Number(opening, xlsx.StyleMoney))
+	st.AddRow(xlsx.Date(day("2024-01-10")), xlsx.Text("Начальное вложение"), xlsx.Number("1000", xlsx.StyleMoney), xlsx.Formula("D4+C5", "1000", xlsx.StyleMoney))
+	st.AddRow(xlsx.Date(day("2024-02-29")), xlsx.Text("Снятие капитала"), xlsx.Number("200.5", xlsx.StyleMoney), xlsx.Formula("D5-C6", "799.5", xlsx.StyleMoney))
+	st.AddRow(xlsx.Empty(), xlsx.Text("Итого"), xlsx.Formula("SUM(C5:C6)", "1200.5", xlsx.StyleMoneyBold))
+
+	var buf bytes.Buffer
+	if err := wb.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseExport(t *testing.T) {
+	rows, errs, err := Parse(exportBook(t, "0"), nil)
+	if err != nil || len(errs) != 0 {
+		t.Fatalf("Parse() = %v, %+v", err, errs)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("rows = %+v, want 2", rows)
+	}
+
+	name := "Константинопольский Константин Константинович"
+	if rows[0].InvestorName != name || rows[0].Kind != models.KindDeposit || rows[0].Amount != 100000 ||
+		!rows[0].Date.Equal(day("2024-01-10")) || rows[0].Line != 5 {
+		t.Errorf("row 0 = %+v", rows[0])
+	}
+	if rows[1].InvestorName != name || rows[1].Kind != models.KindCapitalWithdrawal || rows[1].Amount != -20050 ||
+		!rows[1].Date.Equal(day("2024-02-29")) {
+		t.Errorf("row 1 = %+v", rows[1])
+	}
+
+	// выгрузка за часть периода: остаток на начало не разложить на операции
+	_, errs, err = Parse(exportBook(t, "500"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 || errs[0].Row != 4 || errs[0].Column != string(FieldKind) {
+		t.Errorf("errors = %+v, want opening balance error in row 4", errs)
+	}
+}
+
+func TestParseInvalidFile(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want error
+	}{
+		{name: "no header", data: "a;b;c\n1;2;3\n", want: ErrNoTable},
+		{name: "not a workbook", data: "PK\x03\x04garbage", want: xlsx.ErrNotWorkbook},
+	}
+	for _, tt := range tests {
+		if _, _, err := Parse([]byte(tt.data), nil); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Parse() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// не UTF-8 (например, CSV из Excel в cp1251)
+	if _, _, err := Parse([]byte("\xc4\xe0\xf2\xe0;kind;amount\n"), nil); err == nil {
+		t.Error("Parse() of cp1251 csv: want error")
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    models.Money
+		wantErr bool
+	}{
+		{in: "1234.56", want: 123456},
+		{in: "1 234,56", want: 123456},
+		{in: "1 234,56 руб.", want: 123456},
+		{in: "1 234 ₽", want: 123400},
+		{in: "−1 500,25", want: -150025},
+		{in: "-0,5", want: -50},
+		{in: "1234.5600000000001", want: 123456}, // значение ячейки Excel
+		{in: "", wantErr: true},
+		{in: "1e3", wantErr: true},
+		{in: "NaN", wantErr: true},
+		{in: "1.234,56", wantErr: true},
+		{in: "сто", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseAmount(tt.in)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("parseAmount(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSignedAmount(t *testing.T) {
+	tests := []struct {
+		kind    models.PayoutKind
+		in      models.Money
+		want    models.Money
+		wantErr bool
+	}{
+		{kind: models.KindCapitalWithdrawal, in: 100, want: -100},
+		{kind: models.KindCapitalWithdrawal, in: -100, want: -100},
+		{kind: models.KindProfitWithdrawal, in: 100, want: -100},
+		{kind: models.KindAdjustment, in: -100, want: -100},
+		{kind: models.KindAdjustment, in: 100, want: 100},
+		{kind: models.KindTopup, in: 100, want: 100},
+		{kind: models.KindReinvest, in: 100, want: 100},
+		{kind: models.KindDeposit, in: 100, want: 100},
+		{kind: models.KindTopup, in: -100, wantErr: true},
+		{kind: models.KindReinvest, in: -100, wantErr: true},
+		{kind: models.KindDeposit, in: -100, wantErr: true},
+		{kind: models.KindTopup, in: 0, wantErr: true},
+		{kind: models.KindCapitalWithdrawal, in: 0, wantErr: true},
+		{kind: models.KindAdjustment, in: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := signedAmount(tt.kind, tt.in)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("signedAmount(%s, %d) = %d, %v; want %d, error %v", tt.kind, tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "2024-03-01", want: "2024-03-01"},
+		{in: "01.03.2024", want: "2024-03-01"},
+		{in: "1.3.2024", want: "2024-03-01"},
+		{in: "45352", want: "2024-03-01"},
+		{in: "45352.75", want: "2024-03-01"}, // дата со временем
+		{in: "", wantErr: true},
+		{in: "2024-02-30", wantErr: true},
+		{in: "03/01/2024", wantErr: true},
+		{in: "0", wantErr: true},
+		{in: "-5", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseDate(tt.in)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got.Format("2006-01-02") != tt.want) {
+			t.Errorf("parseDate(%q) = %s, %v; want %s, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	tests := []struct {
+		in   string
+		want models.PayoutKind
+		ok   bool
+	}{
+		{in: "reinvest", want: models.KindReinvest, ok: true},
+		{in: " Capital_Withdrawal ", want: models.KindCapitalWithdrawal, ok: true},
+		{in: "Реинвест", want: models.KindReinvest, ok: true},
+		{in: "снятие  прибыли", want: models.KindProfitWithdrawal, ok: true},
+		{in: "Пополнение капитала", want: models.KindTopup, ok: true},
+		{in: "пополнение", want: models.KindTopup, ok: true},
+		{in: "Изменение вложения", want: models.KindAdjustment, ok: true},
+		{in: "вложение", want: models.KindDeposit, ok: true},
+		{in: "legacy", ok: false},
+		{in: "", ok: false},
+		{in: "бонус", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := parseKind(tt.in)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseKind(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
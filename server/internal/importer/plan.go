@@ -0,0 +1,188 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"invest/internal/ledger"
+	"invest/internal/models"
+)
+
+// Operation — операция, готовая к проведению. Если Payout.InvestorID == 0,
+// она относится к новому инвестору Plan.NewInvestors[NewInvestor].
+type Operation struct {
+	Row         Row
+	Payout      models.Payout
+	NewInvestor int
+}
+
+// Plan — что будет проведено импортом.
+type Plan struct {
+	NewInvestors []string
+	Operations   []Operation
+
+	Errors   []RowError
+	Warnings []RowError
+}
+
+// BuildPlan сопоставляет строки с инвесторами пространства (по ID или ФИО;
+// незнакомые ФИО — новые инвесторы) и проверяет, что снятия не превышают
+// остаток на свою дату и не уводят в минус более поздние снятия
+// (ledger.CheckBackdated). investors — действующие
+// и архивные инвесторы, payouts — все их операции.
+// Точный повтор уже проведённой операции (тот же инвестор, дата, вид и сумма)
+// — ошибка строки, а с allowDuplicates — только предупреждение.
+func BuildPlan(rows []Row, investors []models.Investor, payouts []models.Payout, allowDuplicates bool) *Plan {
+	p := &Plan{}
+
+	byID := make(map[int64]models.Investor, len(investors))
+	byName := make(map[string][]models.Investor, len(investors))
+	for _, inv := range investors {
+		byID[inv.ID] = inv
+		byName[normalize(inv.FullName)] = append(byName[normalize(inv.FullName)], inv)
+	}
+	newIndex := make(map[string]int)
+
+	// уже проведённые операции — чтобы не провести тот же файл дважды
+	type opKey struct {
+		investor int64
+		date     string
+		kind     models.PayoutKind
+		amount   models.Money
+	}
+	existing := make(map[opKey]bool, len(payouts))
+	for _, op := range payouts {
+		if !op.Voided() && op.PeriodDate != nil {
+			existing[opKey{op.InvestorID, op.PeriodDate.Format("2006-01-02"), op.Kind, op.PayoutAmount}] = true
+		}
+	}
+
+	for _, row := range rows {
+		op := Operation{Row: row, NewInvestor: -1}
+
+		switch {
+		case row.InvestorID != 0:
+			inv, ok := byID[row.InvestorID]
+			if !ok {
+				p.fail(row, FieldInvestorID, fmt.Sprintf("investor %d not found", row.InvestorID))
+				continue
+			}
+			if row.InvestorName != "" && normalize(row.InvestorName) != normalize(inv.FullName) {
+				p.fail(row, FieldInvestor, fmt.Sprintf("investor %d is %q, not %q", inv.ID, inv.FullName, row.InvestorName))
+				continue
+			}
+			op.Payout.InvestorID = inv.ID
+
+		default:
+			switch found := byName[normalize(row.InvestorName)]; len(found) {
+			case 0:
+				i, ok := newIndex[normalize(row.InvestorName)]
+				if !ok {
+					i = len(p.NewInvestors)
+					newIndex[normalize(row.InvestorName)] = i
+					p.NewInvestors = append(p.NewInvestors, row.InvestorName)
+				}
+				op.NewInvestor = i
+			case 1:
+				op.Payout.InvestorID = found[0].ID
+			default:
+				p.fail(row, FieldInvestor, fmt.Sprintf("several investors are named %q, use investor_id", row.InvestorName))
+				continue
+			}
+		}
+
+		date := row.Date
+		op.Payout.PeriodDate = &date
+		op.Payout.Kind = row.Kind
+		op.Payout.PayoutAmount = row.Amount
+
+		if existing[opKey{op.Payout.InvestorID, date.Format("2006-01-02"), row.Kind, row.Amount}] && op.NewInvestor < 0 {
+			if !allowDuplicates {
+				p.Errors = append(p.Errors, RowError{
+					Sheet: row.Sheet, Row: row.Line,
+					Error: "the same operation already exists, pass allow_duplicates=true to add it once more",
+				})
+				continue
+			}
+			p.Warnings = append(p.Warnings, RowError{
+				Sheet: row.Sheet, Row: row.Line,
+				Error: "the same operation already exists, it will be added once more",
+			})
+		}
+
+		p.Operations = append(p.Operations, op)
+	}
+
+	p.checkBalances(investors, payouts)
+	return p
+}
+
+func (p *Plan) fail(row Row, f Field, msg string) {
+	p.Errors = append(p.Errors, RowError{Sheet: row.Sheet, Row: row.Line, Column: string(f), Error: msg})
+}
+
+// checkBalances прогоняет операции каждого инвестора через
+// ledger.CheckBackdated; не прошедшие проверку переносятся в Errors.
+func (p *Plan) checkBalances(investors []models.Investor, payouts []models.Payout) {
+	// операции по инвесторам: существующие — по ID, новые — по отрицательному ключу
+	key := func(op Operation) int64 {
+		if op.Payout.InvestorID != 0 {
+			return op.Payout.InvestorID
+		}
+		return -1 - int64(op.NewInvestor)
+	}
+	groups := make(map[int64][]int)
+	for i, op := range p.Operations {
+		groups[key(op)] = append(groups[key(op)], i)
+	}
+
+	byID := make(map[int64]models.Investor, len(investors))
+	for _, inv := range investors {
+		byID[inv.ID] = inv
+	}
+
+	failed := make(map[int]error)
+	for k, idx := range groups {
+		var inv models.Investor
+		var history []models.Payout
+		if k > 0 {
+			inv = byID[k]
+			for _, op := range payouts {
+				if op.InvestorID == k {
+					history = append(history, op)
+				}
+			}
+		}
+
+		added := make([]models.Payout, len(idx))
+		for j, i := range idx {
+			added[j] = p.Operations[i].Payout
+		}
+		for j, err := range ledger.CheckBackdated(inv, history, added) {
+			if err != nil {
+				failed[idx[j]] = err
+			}
+		}
+	}
+	if len(failed) == 0 {
+		return
+	}
+
+	kept := p.Operations[:0]
+	for i, op := range p.Operations {
+		if err, ok := failed[i]; ok {
+			p.fail(op.Row, FieldAmount, balanceMessage(err))
+			continue
+		}
+		kept = append(kept, op)
+	}
+	p.Operations = kept
+}
+
+func balanceMessage(err error) string {
+	var insufficient *ledger.InsufficientFundsError
+	if errors.As(err, &insufficient) {
+		return fmt.Sprintf("insufficient funds on this date or for a later withdrawal: available %s, requested %s",
+			insufficient.Available, insufficient.Requested)
+	}
+	return err.Error()
+}
@@ -0,0 +1,122 @@
+package importer
+
+import (
+	"invest/internal/models"
+	"testing"
+	"time"
+)
+
+func TestBuildPlan(t *testing.T) {
+	investors := []models.Investor{
+		{ID: 1, FullName: "Иванов Иван"},
+		{ID: 2, FullName: "Петров Пётр"},
+		{ID: 3, FullName: "Сидоров Сидор"},
+		{ID: 4, FullName: "сидоров  сидор"},
+	}
+	at := func(s string) *time.Time {
+		d := day(s)
+		return &d
+	}
+	payouts := []models.Payout{
+		{InvestorID: 1, PeriodDate: at("2024-01-01"), Kind: models.KindDeposit, PayoutAmount: 10000},
+		{InvestorID: 1, PeriodDate: at("2024-03-01"), Kind: models.KindCapitalWithdrawal, PayoutAmount: -10000},
+		{InvestorID: 2, PeriodDate: at("2024-01-01"), Kind: models.KindDeposit, PayoutAmount: 50000},
+	}
+
+	rows := []Row{
+		// 2: по ID, повтор уже проведённой операции — предупреждение (allowDuplicates)
+		{Line: 2, InvestorID: 2, Date: day("2024-01-01"), Kind: models.KindDeposit, Amount: 50000},
+		// 3: по ФИО без учёта регистра и «ё»
+		{Line: 3, InvestorName: "петров петр", Date: day("2024-02-01"), Kind: models.KindTopup, Amount: 1000},
+		// 4: ID и ФИО не совпадают
+		{Line: 4, InvestorID: 2, InvestorName: "Иванов Иван", Date: day("2024-02-01"), Kind: models.KindTopup, Amount: 1000},
+		// 5: нет такого ID
+		{Line: 5, InvestorID: 99, Date: day("2024-02-01"), Kind: models.KindTopup, Amount: 1000},
+		// 6: два инвестора с таким ФИО
+		{Line: 6, InvestorName: "Сидоров Сидор", Date: day("2024-02-01"), Kind: models.KindTopup, Amount: 1000},
+		// 7, 8: новый инвестор — один на обе строки
+		{Line: 7, InvestorName: "Новиков Николай", Date: day("2024-01-01"), Kind: models.KindDeposit, Amount: 20000},
+		{Line: 8, InvestorName: "новиков николай", Date: day("2024-02-01"), Kind: models.KindCapitalWithdrawal, Amount: -5000},
+		// 9: снятие у нового инвестора больше остатка
+		{Line: 9, InvestorName: "Новиков Николай", Date: day("2024-03-01"), Kind: models.KindCapitalWithdrawal, Amount: -20000},
+		// 10: февральское снятие увело бы в минус уже проведённое мартовское
+		{Line: 10, InvestorID: 1, Date: day("2024-02-01"), Kind: models.KindCapitalWithdrawal, Amount: -10000},
+	}
+
+	p := BuildPlan(rows, investors, payouts, true)
+
+	if len(p.NewInvestors) != 1 || p.NewInvestors[0] != "Новиков Николай" {
+		t.Errorf("NewInvestors = %q, want [Новиков Николай]", p.NewInvestors)
+	}
+
+	type planned struct {
+		line        int
+		investorID  int64
+		newInvestor int
+	}
+	want := []planned{
+		{line: 2, investorID: 2, newInvestor: -1},
+		{line: 3, investorID: 2, newInvestor: -1},
+		{line: 7, newInvestor: 0},
+		{line: 8, newInvestor: 0},
+	}
+	if len(p.Operations) != len(want) {
+		t.Fatalf("operations = %+v, want %d", p.Operations, len(want))
+	}
+	for i, w := range want {
+		op := p.Operations[i]
+		got := planned{line: op.Row.Line, investorID: op.Payout.InvestorID, newInvestor: op.NewInvestor}
+		if got != w {
+			t.Errorf("operation %d = %+v, want %+v", i, got, w)
+		}
+		if op.Payout.PayoutAmount != op.Row.Amount || op.Payout.Kind != op.Row.Kind || !op.Payout.PeriodDate.Equal(op.Row.Date) {
+			t.Errorf("operation %d payout = %+v, row %+v", i, op.Payout, op.Row)
+		}
+	}
+
+	wantErrs := map[int]Field{4: FieldInvestor, 5: FieldInvestorID, 6: FieldInvestor, 9: FieldAmount, 10: FieldAmount}
+	if len(p.Errors) != len(wantErrs) {
+		t.Errorf("errors = %+v, want rows %v", p.Errors, wantErrs)
+	}
+	for _, e := range p.Errors {
+		if f, ok := wantErrs[e.Row]; !ok || e.Column != string(f) {
+			t.Errorf("unexpected error %+v", e)
+		}
+	}
+
+	if len(p.Warnings) != 1 || p.Warnings[0].Row != 2 {
+		t.Errorf("warnings = %+v, want one for row 2", p.Warnings)
+	}
+}
+
+func TestBuildPlanDuplicates(t *testing.T) {
+	investors := []models.Investor{{ID: 1, FullName: "Иванов Иван"}}
+	jan := day("2024-01-01")
+	payouts := []models.Payout{
+		{InvestorID: 1, PeriodDate: &jan, Kind: models.KindDeposit, PayoutAmount: 10000},
+	}
+	rows := []Row{
+		{Line: 2, InvestorID: 1, Date: day("2024-01-01"), Kind: models.KindDeposit, Amount: 10000},
+		{Line: 3, InvestorID: 1, Date: day("2024-01-01"), Kind: models.KindDeposit, Amount: 20000},
+	}
+
+	tests := []struct {
+		allow                    bool
+		operations, errs, warned int
+	}{
+		{allow: false, operations: 1, errs: 1},
+		{allow: true, operations: 2, warned: 1},
+	}
+	for _, tt := range tests {
+		p := BuildPlan(rows, investors, payouts, tt.allow)
+		if len(p.Operations) != tt.operations || len(p.Errors) != tt.errs || len(p.Warnings) != tt.warned {
+			t.Errorf("allowDuplicates=%v: operations %d, errors %+v, warnings %+v; want %d, %d, %d",
+				tt.allow, len(p.Operations), p.Errors, p.Warnings, tt.operations, tt.errs, tt.warned)
+		}
+		for _, e := range append(p.Errors, p.Warnings...) {
+			if e.Row != 2 {
+				t.Errorf("allowDuplicates=%v: unexpected %+v", tt.allow, e)
+			}
+		}
+	}
+}
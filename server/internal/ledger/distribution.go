@@ -0,0 +1,115 @@
+package ledger
+
+import (
+	"invest/internal/models"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// ========================
+//   PRO-RATA РАСПРЕДЕЛЕНИЕ
+// ========================
+
+// CapitalDays — сумма капитала инвестора (в копейках) по всем дням периода [from, to].
+// Операция, датированная днём d, меняет капитал начиная с этого же дня.
+// Отрицательный капитал считается нулевым — в распределении он не участвует.
+func CapitalDays(inv models.Investor, payouts []models.Payout, from, to time.Time) *big.Int {
+	total := new(big.Int)
+	if to.Before(from) {
+		return total
+	}
+
+	// капитал на начало периода — всё, что было до from
+	capital := ComputeAt(inv, payouts, from.AddDate(0, 0, -1)).CapitalNow
+
+	// изменения капитала внутри периода, по дням
+	// ключ — дата строкой, чтобы не зависеть от часового пояса time.Time
+	deltas := make(map[string]models.Money)
+	for _, p := range payouts {
+		if p.InvestorID != inv.ID || p.PeriodDate == nil || p.Voided() {
+			continue
+		}
+		d := *p.PeriodDate
+		if d.Before(from) || d.After(to) {
+			continue
+		}
+		key := d.Format("2006-01-02")
+		deltas[key] = deltas[key].Add(CapitalDelta(p))
+	}
+
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		capital = capital.Add(deltas[d.Format("2006-01-02")])
+		if capital > 0 {
+			total.Add(total, big.NewInt(int64(capital)))
+		}
+	}
+	return total
+}
+
+// Share — доля инвестора в распределении.
+type Share struct {
+	InvestorID  int64        `json:"investor_id"`
+	CapitalDays string       `json:"capital_days"`
+	Amount      models.Money `json:"amount"`
+}
+
+// Distribute делит total пропорционально весам.
+//
+// Округление: каждая доля округляется вниз до копейки, оставшиеся копейки
+// раздаются по одной инвесторам с наибольшим отброшенным остатком;
+// при равных остатках — по возрастанию ID инвестора. Сумма долей всегда равна total.
+// Инвесторы с нулевым весом в результат не попадают.
+func Distribute(total models.Money, weights map[int64]*big.Int) []Share {
+	sum := new(big.Int)
+	ids := make([]int64, 0, len(weights))
+	for id, w := range weights {
+		if w.Sign() <= 0 {
+			continue
+		}
+		sum.Add(sum, w)
+		ids = append(ids, id)
+	}
+	if sum.Sign() == 0 || total <= 0 {
+		return nil
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	type part struct {
+		share Share
+		rem   *big.Int
+	}
+
+	parts := make([]part, 0, len(ids))
+	allocated := models.Money(0)
+	t := big.NewInt(int64(total))
+
+	for _, id := range ids {
+		w := weights[id]
+
+		q, rem := new(big.Int).QuoRem(new(big.Int).Mul(t, w), sum, new(big.Int))
+		amount := models.Money(q.Int64())
+		allocated = allocated.Add(amount)
+
+		parts = append(parts, part{
+			share: Share{InvestorID: id, CapitalDays: w.String(), Amount: amount},
+			rem:   rem,
+		})
+	}
+
+	// остаток копеек — детерминированно
+	sort.SliceStable(parts, func(i, j int) bool {
+		return parts[i].rem.Cmp(parts[j].rem) > 0
+	})
+	for i := 0; allocated < total; i++ {
+		parts[i].share.Amount++
+		allocated++
+	}
+
+	out := make([]Share, 0, len(parts))
+	for _, p := range parts {
+		out = append(out, p.share)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].InvestorID < out[j].InvestorID })
+	return out
+}
@@ -0,0 +1,181 @@
+package ledger
+
+import (
+	"invest/internal/models"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDistribute(t *testing.T) {
+	huge := func(s string) *big.Int {
+		v, _ := new(big.Int).SetString(s, 10)
+		return v
+	}
+
+	tests := []struct {
+		name    string
+		total   models.Money
+		weights map[int64]*big.Int
+		want    map[int64]models.Money
+	}{
+		{
+			name:    "equal weights, leftover kopeck to lowest id",
+			total:   100,
+			weights: map[int64]*big.Int{3: big.NewInt(1), 1: big.NewInt(1), 2: big.NewInt(1)},
+			want:    map[int64]models.Money{1: 34, 2: 33, 3: 33},
+		},
+		{
+			name:    "leftover to largest remainder",
+			total:   1000,
+			weights: map[int64]*big.Int{1: big.NewInt(1), 2: big.NewInt(2)},
+			want:    map[int64]models.Money{1: 333, 2: 667},
+		},
+		{
+			name:    "several leftover kopecks",
+			total:   10,
+			weights: map[int64]*big.Int{1: big.NewInt(1), 2: big.NewInt(1), 3: big.NewInt(1), 4: big.NewInt(4)},
+			want:    map[int64]models.Money{1: 2, 2: 1, 3: 1, 4: 6},
+		},
+		{
+			name:    "exact split",
+			total:   900,
+			weights: map[int64]*big.Int{1: big.NewInt(1), 2: big.NewInt(2)},
+			want:    map[int64]models.Money{1: 300, 2: 600},
+		},
+		{
+			name:    "zero and negative weights are left out",
+			total:   10,
+			weights: map[int64]*big.Int{1: big.NewInt(0), 2: big.NewInt(5), 3: big.NewInt(-5)},
+			want:    map[int64]models.Money{2: 10},
+		},
+		{
+			name:    "weights beyond int64",
+			total:   100,
+			weights: map[int64]*big.Int{1: huge("1000000000000000000000000000000"), 2: huge("3000000000000000000000000000000")},
+			want:    map[int64]models.Money{1: 25, 2: 75},
+		},
+		{
+			name:    "no weights",
+			total:   100,
+			weights: map[int64]*big.Int{1: big.NewInt(0)},
+			want:    map[int64]models.Money{},
+		},
+		{
+			name:    "nothing to distribute",
+			total:   0,
+			weights: map[int64]*big.Int{1: big.NewInt(1)},
+			want:    map[int64]models.Money{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares := Distribute(tt.total, tt.weights)
+
+			got := make(map[int64]models.Money, len(shares))
+			var sum models.Money
+			for i, sh := range shares {
+				if i > 0 && shares[i-1].InvestorID >= sh.InvestorID {
+					t.Errorf("shares are not sorted by investor id: %v", shares)
+				}
+				got[sh.InvestorID] = sh.Amount
+				sum = sum.Add(sh.Amount)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Distribute() = %v, want %v", got, tt.want)
+			}
+			if len(shares) > 0 && sum != tt.total {
+				t.Errorf("sum of shares = %d, want %d", sum, tt.total)
+			}
+		})
+	}
+}
+
+func TestCapitalDays(t *testing.T) {
+	day := func(d int) *time.Time {
+		v := time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+		return &v
+	}
+	op := func(d int, kind models.PayoutKind, amount models.Money) models.Payout {
+		return models.Payout{InvestorID: 1, PeriodDate: day(d), Kind: kind, PayoutAmount: amount}
+	}
+	voided := op(5, models.KindTopup, 100000)
+	voided.ReversedByID = new(int64)
+
+	inv := models.Investor{ID: 1}
+	from, to := *day(1), *day(10)
+
+	tests := []struct {
+		name    string
+		payouts []models.Payout
+		want    int64
+	}{
+		{
+			name:    "capital before the period",
+			payouts: []models.Payout{op(1, models.KindDeposit, 10000)},
+			want:    10 * 10000,
+		},
+		{
+			name: "topup counts from its own day",
+			payouts: []models.Payout{
+				op(1, models.KindDeposit, 10000),
+				op(6, models.KindTopup, 10000),
+			},
+			want: 5*10000 + 5*20000,
+		},
+		{
+			name: "profit withdrawal does not change capital",
+			payouts: []models.Payout{
+				op(1, models.KindDeposit, 10000),
+				op(3, models.KindProfitWithdrawal, 500),
+			},
+			want: 10 * 10000,
+		},
+		{
+			name: "negative capital counts as zero",
+			payouts: []models.Payout{
+				op(1, models.KindDeposit, 10000),
+				op(4, models.KindCapitalWithdrawal, -20000),
+				op(9, models.KindTopup, 20000),
+			},
+			want: 3*10000 + 2*10000,
+		},
+		{
+			name: "reversed operations are ignored",
+			payouts: []models.Payout{
+				op(1, models.KindDeposit, 10000),
+				voided,
+			},
+			want: 10 * 10000,
+		},
+		{
+			name: "other investors are ignored",
+			payouts: []models.Payout{
+				op(1, models.KindDeposit, 10000),
+				{InvestorID: 2, PeriodDate: day(1), Kind: models.KindDeposit, PayoutAmount: 99999},
+			},
+			want: 10 * 10000,
+		},
+		{
+			name:    "operations after the period",
+			payouts: []models.Payout{op(11, models.KindDeposit, 10000)},
+			want:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CapitalDays(inv, tt.payouts, from, to)
+			if got.Cmp(big.NewInt(tt.want)) != 0 {
+				t.Errorf("CapitalDays() = %s, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if got := CapitalDays(inv, []models.Payout{op(1, models.KindDeposit, 10000)}, to, from); got.Sign() != 0 {
+		t.Errorf("CapitalDays() with to before from = %s, want 0", got)
+	}
+}
@@ -0,0 +1,140 @@
+package ledger
+
+import (
+	"invest/internal/models"
+	"time"
+)
+
+// ========================
+//    МЕСЯЧНАЯ СЕТКА
+// ========================
+
+// KindTotals — суммы операций по типам. Снятия — положительными числами.
+type KindTotals struct {
+	Invested          models.Money `json:"invested"` // deposit + adjustment, со знаком
+	Reinvest          models.Money `json:"reinvest"`
+	Topup             models.Money `json:"topup"`
+	ProfitWithdrawal  models.Money `json:"profit_withdrawal"`
+	CapitalWithdrawal models.Money `json:"capital_withdrawal"`
+}
+
+func (t *KindTotals) add(p models.Payout) {
+	switch p.Kind {
+	case models.KindDeposit, models.KindAdjustment:
+		t.Invested = t.Invested.Add(p.PayoutAmount)
+	case models.KindReinvest:
+		t.Reinvest = t.Reinvest.Add(p.PayoutAmount)
+	case models.KindTopup:
+		t.Topup = t.Topup.Add(p.PayoutAmount)
+	case models.KindProfitWithdrawal:
+		t.ProfitWithdrawal = t.ProfitWithdrawal.Add(p.PayoutAmount.Abs())
+	case models.KindCapitalWithdrawal:
+		t.CapitalWithdrawal = t.CapitalWithdrawal.Add(p.PayoutAmount.Abs())
+	}
+}
+
+func (t *KindTotals) merge(o KindTotals) {
+	t.Invested = t.Invested.Add(o.Invested)
+	t.Reinvest = t.Reinvest.Add(o.Reinvest)
+	t.Topup = t.Topup.Add(o.Topup)
+	t.ProfitWithdrawal = t.ProfitWithdrawal.Add(o.ProfitWithdrawal)
+	t.CapitalWithdrawal = t.CapitalWithdrawal.Add(o.CapitalWithdrawal)
+}
+
+// GridCell — операции одного инвестора за месяц и его капитал
+// на начало и конец месяца.
+type GridCell struct {
+	InvestorID int64 `json:"investor_id"`
+
+	// операции месяца по дате; изменения вложенной суммы (deposit /
+	// adjustment) сюда не попадают — они видны в Totals.Invested и капитале
+	Operations []models.Payout `json:"operations"`
+	Totals     KindTotals      `json:"totals"`
+
+	CapitalStart models.Money `json:"capital_start"`
+	CapitalEnd   models.Money `json:"capital_end"`
+}
+
+// GridMonth — строка сетки: месяц по всем инвесторам.
+type GridMonth struct {
+	Month string `json:"month"` // YYYY-MM
+
+	// сколько колонок нужно месяцу в таблице: максимум операций у одного инвестора
+	Slots int `json:"slots"`
+
+	Investors []GridCell `json:"investors"`
+
+	Totals     KindTotals   `json:"totals"`
+	CapitalEnd models.Money `json:"capital_end"`
+}
+
+// MonthlyGrid — месяц × инвестор: то, что рисуют таблица, Excel и PDF.
+type MonthlyGrid struct {
+	From   string      `json:"from"` // YYYY-MM
+	To     string      `json:"to"`
+	Months []GridMonth `json:"months"`
+}
+
+// BuildMonthlyGrid строит сетку за месяцы [from, to] (любые даты внутри
+// месяцев). Отменённые операции и сторно не учитываются; операции без даты
+// влияют только на капитал.
+func BuildMonthlyGrid(investors []models.Investor, payouts []models.Payout, from, to time.Time) MonthlyGrid {
+	first := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	grid := MonthlyGrid{
+		From:   first.Format("2006-01"),
+		To:     last.Format("2006-01"),
+		Months: []GridMonth{},
+	}
+
+	byInvestor := make(map[int64][]models.Payout, len(investors))
+	for _, p := range payouts {
+		byInvestor[p.InvestorID] = append(byInvestor[p.InvestorID], p)
+	}
+
+	// капитал каждого инвестора на начало первого месяца
+	capital := make(map[int64]models.Money, len(investors))
+	for _, inv := range investors {
+		capital[inv.ID] = ComputeAt(inv, byInvestor[inv.ID], first.AddDate(0, 0, -1)).CapitalNow
+	}
+
+	for m := first; !m.After(last); m = m.AddDate(0, 1, 0) {
+		month := GridMonth{
+			Month:     m.Format("2006-01"),
+			Investors: make([]GridCell, 0, len(investors)),
+		}
+
+		for _, inv := range investors {
+			cell := GridCell{
+				InvestorID:   inv.ID,
+				Operations:   []models.Payout{},
+				CapitalStart: capital[inv.ID],
+			}
+
+			end := cell.CapitalStart
+			for _, p := range byInvestor[inv.ID] {
+				// месяц сравниваем строкой, чтобы не зависеть от часового пояса time.Time
+				if p.Voided() || p.PeriodDate == nil || p.PeriodDate.Format("2006-01") != month.Month {
+					continue
+				}
+
+				end = end.Add(CapitalDelta(p))
+				cell.Totals.add(p)
+				if p.Kind != models.KindDeposit && p.Kind != models.KindAdjustment {
+					cell.Operations = append(cell.Operations, p)
+				}
+			}
+			cell.CapitalEnd = end
+			capital[inv.ID] = end
+
+			month.Slots = max(month.Slots, len(cell.Operations))
+			month.Totals.merge(cell.Totals)
+			month.CapitalEnd = month.CapitalEnd.Add(end)
+			month.Investors = append(month.Investors, cell)
+		}
+
+		grid.Months = append(grid.Months, month)
+	}
+	return grid
+}
@@ -0,0 +1,246 @@
+package ledger
+
+import (
+	"invest/internal/models"
+	"sort"
+	"time"
+)
+
+// Balance — сводные показатели инвестора, посчитанные по его операциям.
+// Правила совпадают с тем, что раньше считалось на клиенте в useInvestData.js.
+type Balance struct {
+	InvestorID int64 `json:"investor_id"`
+
+	InvestedAmount   models.Money `json:"invested_amount"`
+	ReinvestedTotal  models.Money `json:"reinvested_total"`
+	TopupsTotal      models.Money `json:"topups_total"`
+	WithdrawnCapital models.Money `json:"withdrawn_capital"`
+	WithdrawnProfit  models.Money `json:"withdrawn_profit"`
+
+	CapitalNow         models.Money `json:"capital_now"`
+	NetProfit          models.Money `json:"net_profit"`
+	TotalProfitAllTime models.Money `json:"total_profit_all_time"`
+}
+
+// Compute считает баланс одного инвестора. Операции других инвесторов
+// в payouts игнорируются, поэтому можно передавать общий список.
+// Вложенная сумма берётся из операций deposit / adjustment, а не из inv.
+func Compute(inv models.Investor, payouts []models.Payout) Balance {
+	b := Balance{InvestorID: inv.ID}
+
+	for _, p := range payouts {
+		if p.InvestorID != inv.ID || p.Voided() {
+			continue
+		}
+
+		switch p.Kind {
+		case models.KindDeposit, models.KindAdjustment:
+			b.InvestedAmount = b.InvestedAmount.Add(p.PayoutAmount)
+		case models.KindReinvest:
+			b.ReinvestedTotal = b.ReinvestedTotal.Add(p.PayoutAmount)
+			b.TotalProfitAllTime = b.TotalProfitAllTime.Add(p.PayoutAmount.Abs())
+		case models.KindTopup:
+			b.TopupsTotal = b.TopupsTotal.Add(p.PayoutAmount)
+		case models.KindCapitalWithdrawal:
+			// снятие капитала хранится с отрицательным знаком
+			b.WithdrawnCapital = b.WithdrawnCapital.Add(p.PayoutAmount.Abs())
+		case models.KindProfitWithdrawal:
+			b.WithdrawnProfit = b.WithdrawnProfit.Add(p.PayoutAmount.Abs())
+			b.TotalProfitAllTime = b.TotalProfitAllTime.Add(p.PayoutAmount.Abs())
+		}
+	}
+
+	b.CapitalNow = b.InvestedAmount.Add(b.ReinvestedTotal).Add(b.TopupsTotal).Sub(b.WithdrawnCapital)
+
+	// чистая прибыль не уходит в минус
+	b.NetProfit = b.ReinvestedTotal.Sub(b.WithdrawnProfit)
+	if b.NetProfit < 0 {
+		b.NetProfit = 0
+	}
+
+	return b
+}
+
+// CapitalDelta — на сколько операция меняет капитал (те же правила, что в Compute).
+func CapitalDelta(p models.Payout) models.Money {
+	switch p.Kind {
+	case models.KindReinvest, models.KindTopup, models.KindDeposit, models.KindAdjustment:
+		return p.PayoutAmount
+	case models.KindCapitalWithdrawal:
+		return p.PayoutAmount.Abs().Neg()
+	}
+	return 0
+}
+
+// ComputeAt — баланс на дату: учитываются только операции не позже at.
+func ComputeAt(inv models.Investor, payouts []models.Payout, at time.Time) Balance {
+	filtered := make([]models.Payout, 0, len(payouts))
+	for _, p := range payouts {
+		if p.PeriodDate != nil && p.PeriodDate.After(at) {
+			continue
+		}
+		filtered = append(filtered, p)
+	}
+	return Compute(inv, filtered)
+}
+
+// ComputeAll считает балансы для списка инвесторов за один проход по операциям.
+func ComputeAll(investors []models.Investor, payouts []models.Payout) map[int64]Balance {
+	byInvestor := make(map[int64][]models.Payout, len(investors))
+	for _, p := range payouts {
+		byInvestor[p.InvestorID] = append(byInvestor[p.InvestorID], p)
+	}
+
+	out := make(map[int64]Balance, len(investors))
+	for _, inv := range investors {
+		out[inv.ID] = Compute(inv, byInvestor[inv.ID])
+	}
+	return out
+}
+
+// ========================
+//   ПРОВЕРКА ОСТАТКА
+// ========================
+
+// InsufficientFundsError — попытка снять больше, чем доступно.
+type InsufficientFundsError struct {
+	InvestorID int64
+	Kind       models.PayoutKind
+	Available  models.Money
+	Requested  models.Money
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return "insufficient funds for " + string(e.Kind) +
+		": available " + e.Available.String() + ", requested " + e.Requested.String()
+}
+
+// CheckAvailable проверяет, что снятие прибыли не превышает чистую прибыль
+// (реинвестированное за вычетом снятого), а операция, уменьшающая капитал
+// (снятие, уменьшение вложенной суммы или любая другая с отрицательным
+// CapitalDelta), — текущий капитал. Остальные операции не ограничиваются.
+func CheckAvailable(b Balance, p models.Payout) error {
+	if p.Kind == models.KindProfitWithdrawal {
+		return checkLimit(b, p, b.NetProfit)
+	}
+	if CapitalDelta(p) >= 0 {
+		return nil
+	}
+	return checkLimit(b, p, b.CapitalNow)
+}
+
+func checkLimit(b Balance, p models.Payout, available models.Money) error {
+	if available < 0 {
+		available = 0
+	}
+
+	requested := p.PayoutAmount.Abs()
+	if requested > available {
+		return &InsufficientFundsError{
+			InvestorID: b.InvestorID,
+			Kind:       p.Kind,
+			Available:  available,
+			Requested:  requested,
+		}
+	}
+	return nil
+}
+
+// CheckReversal проверяет, что отмена операции не уводит капитал в минус
+// (например, сторно пополнения, которое уже частично снято).
+func CheckReversal(b Balance, p models.Payout) error {
+	delta := CapitalDelta(p)
+	if delta <= 0 || b.CapitalNow-delta >= 0 {
+		return nil
+	}
+
+	available := b.CapitalNow
+	if available < 0 {
+		available = 0
+	}
+	return &InsufficientFundsError{
+		InvestorID: b.InvestorID,
+		Kind:       p.Kind,
+		Available:  available,
+		Requested:  delta,
+	}
+}
+
+// CheckBackdated проверяет операции, добавляемые задним числом (импорт):
+// каждая из added в порядке дат проходит CheckAvailable против баланса
+// на свою дату — по existing и уже проверенным операциям из added.
+// Снятие задним числом уменьшает капитал (или чистую прибыль) и на всех
+// более поздних датах, поэтому доступным считается наименьшее значение на
+// свою дату и на датах следующих снятий того же рода: иначе уже
+// проведённое позже снятие ушло бы в минус.
+// Результат выровнен с added: nil — операция допустима. Не прошедшие
+// проверку операции в дальнейший баланс не входят.
+func CheckBackdated(inv models.Investor, existing, added []models.Payout) []error {
+	order := make([]int, len(added))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return dateBefore(added[order[a]].PeriodDate, added[order[b]].PeriodDate)
+	})
+
+	errs := make([]error, len(added))
+	history := append(make([]models.Payout, 0, len(existing)+len(added)), existing...)
+	for _, i := range order {
+		p := added[i]
+
+		var b Balance
+		if p.PeriodDate != nil {
+			b = ComputeAt(inv, history, *p.PeriodDate)
+		} else {
+			b = Compute(inv, history)
+		}
+
+		switch {
+		case p.Kind == models.KindProfitWithdrawal:
+			b.NetProfit = lowestAfter(inv, history, p.PeriodDate, b.NetProfit, isProfitWithdrawal, profitLeft)
+		case CapitalDelta(p) < 0:
+			b.CapitalNow = lowestAfter(inv, history, p.PeriodDate, b.CapitalNow, decreasesCapital, capitalLeft)
+		}
+
+		if errs[i] = CheckAvailable(b, p); errs[i] == nil {
+			history = append(history, p)
+		}
+	}
+	return errs
+}
+
+// lowestAfter — наименьшее из current (значения на дату from) и значения
+// value на датах более поздних операций, для которых limited — true.
+func lowestAfter(inv models.Investor, history []models.Payout, from *time.Time, current models.Money,
+	limited func(models.Payout) bool, value func(Balance) models.Money) models.Money {
+	for _, q := range history {
+		if q.InvestorID != inv.ID || q.Voided() || q.PeriodDate == nil ||
+			!limited(q) || !dateBefore(from, q.PeriodDate) {
+			continue
+		}
+		if v := value(ComputeAt(inv, history, *q.PeriodDate)); v < current {
+			current = v
+		}
+	}
+	return current
+}
+
+func decreasesCapital(p models.Payout) bool   { return CapitalDelta(p) < 0 }
+func isProfitWithdrawal(p models.Payout) bool { return p.Kind == models.KindProfitWithdrawal }
+func capitalLeft(b Balance) models.Money      { return b.CapitalNow }
+
+// profitLeft — чистая прибыль без ограничения снизу нулём, как в Compute:
+// иначе уход в минус на более поздней дате был бы не виден.
+func profitLeft(b Balance) models.Money { return b.ReinvestedTotal.Sub(b.WithdrawnProfit) }
+
+// dateBefore — операции без даты идут первыми, как в ComputeAt.
+func dateBefore(a, b *time.Time) bool {
+	switch {
+	case a == nil:
+		return b != nil
+	case b == nil:
+		return false
+	}
+	return a.Before(*b)
+}
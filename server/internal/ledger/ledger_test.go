@@ -0,0 +1,222 @@
+package ledger
+
+import (
+	"errors"
+	"invest/internal/models"
+	"testing"
+	"time"
+)
+
+func date(month, day int) *time.Time {
+	t := time.Date(2024, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func payout(d *time.Time, kind models.PayoutKind, amount models.Money) models.Payout {
+	return models.Payout{InvestorID: 1, PeriodDate: d, Kind: kind, PayoutAmount: amount}
+}
+
+func TestComputeAt(t *testing.T) {
+	inv := models.Investor{ID: 1}
+	reversed := payout(date(2, 1), models.KindTopup, 50000)
+	reversed.ReversedByID = new(int64)
+
+	payouts := []models.Payout{
+		payout(date(1, 1), models.KindDeposit, 100000),
+		payout(date(1, 31), models.KindReinvest, 5000),
+		payout(date(2, 1), models.KindTopup, 20000),
+		reversed,
+		payout(date(2, 29), models.KindProfitWithdrawal, 3000),
+		payout(date(3, 15), models.KindCapitalWithdrawal, -40000),
+		payout(nil, models.KindAdjustment, 1000), // без даты — всегда учитывается
+		{InvestorID: 2, PeriodDate: date(1, 1), Kind: models.KindDeposit, PayoutAmount: 99999},
+	}
+
+	tests := []struct {
+		at               string
+		capital, profit  models.Money
+		withdrawnCapital models.Money
+		totalProfit      models.Money
+	}{
+		{at: "2023-12-31", capital: 1000},
+		{at: "2024-01-01", capital: 101000},
+		{at: "2024-01-31", capital: 106000, profit: 5000, totalProfit: 5000},
+		{at: "2024-02-29", capital: 126000, profit: 2000, totalProfit: 8000},
+		{at: "2024-03-15", capital: 86000, profit: 2000, withdrawnCapital: 40000, totalProfit: 8000},
+	}
+
+	for _, tt := range tests {
+		at, _ := time.Parse("2006-01-02", tt.at)
+		b := ComputeAt(inv, payouts, at)
+		if b.CapitalNow != tt.capital || b.NetProfit != tt.profit ||
+			b.WithdrawnCapital != tt.withdrawnCapital || b.TotalProfitAllTime != tt.totalProfit {
+			t.Errorf("ComputeAt(%s) = capital %s, net profit %s, withdrawn %s, total profit %s; want %s, %s, %s, %s",
+				tt.at, b.CapitalNow, b.NetProfit, b.WithdrawnCapital, b.TotalProfitAllTime,
+				tt.capital, tt.profit, tt.withdrawnCapital, tt.totalProfit)
+		}
+	}
+}
+
+func TestCheckBackdated(t *testing.T) {
+	inv := models.Investor{ID: 1}
+	deposit := payout(date(1, 1), models.KindDeposit, 10000)
+
+	tests := []struct {
+		name     string
+		existing []models.Payout
+		added    []models.Payout
+		wantErr  []bool
+	}{
+		{
+			name:     "withdrawal within balance on its date",
+			existing: []models.Payout{deposit},
+			added:    []models.Payout{payout(date(2, 1), models.KindCapitalWithdrawal, -10000)},
+			wantErr:  []bool{false},
+		},
+		{
+			name:     "withdrawal before the deposit",
+			existing: []models.Payout{payout(date(3, 1), models.KindDeposit, 10000)},
+			added:    []models.Payout{payout(date(2, 1), models.KindCapitalWithdrawal, -10000)},
+			wantErr:  []bool{true},
+		},
+		{
+			// январь +100, март −100 уже проведены: февральское снятие увело бы март в минус
+			name:     "backdated withdrawal overdraws a later one",
+			existing: []models.Payout{deposit, payout(date(3, 1), models.KindCapitalWithdrawal, -10000)},
+			added:    []models.Payout{payout(date(2, 1), models.KindCapitalWithdrawal, -10000)},
+			wantErr:  []bool{true},
+		},
+		{
+			name:     "backdated withdrawal leaves enough for a later one",
+			existing: []models.Payout{deposit, payout(date(3, 1), models.KindCapitalWithdrawal, -6000)},
+			added:    []models.Payout{payout(date(2, 1), models.KindCapitalWithdrawal, -4000)},
+			wantErr:  []bool{false},
+		},
+		{
+			name:     "later topup covers a later withdrawal",
+			existing: []models.Payout{deposit, payout(date(3, 1), models.KindTopup, 10000), payout(date(4, 1), models.KindCapitalWithdrawal, -10000)},
+			added:    []models.Payout{payout(date(2, 1), models.KindCapitalWithdrawal, -10000)},
+			wantErr:  []bool{false},
+		},
+		{
+			name:     "added operations are checked in date order",
+			existing: nil,
+			added: []models.Payout{
+				payout(date(2, 1), models.KindCapitalWithdrawal, -10000),
+				payout(date(1, 1), models.KindDeposit, 10000),
+			},
+			wantErr: []bool{false, false},
+		},
+		{
+			name:     "only the overdrawing one fails",
+			existing: []models.Payout{deposit},
+			added: []models.Payout{
+				payout(date(2, 1), models.KindCapitalWithdrawal, -6000),
+				payout(date(3, 1), models.KindCapitalWithdrawal, -6000),
+				payout(date(4, 1), models.KindCapitalWithdrawal, -4000),
+			},
+			wantErr: []bool{false, true, false},
+		},
+		{
+			name:     "later of two added withdrawals fails",
+			existing: []models.Payout{deposit},
+			added: []models.Payout{
+				payout(date(3, 1), models.KindCapitalWithdrawal, -10000),
+				payout(date(2, 1), models.KindCapitalWithdrawal, -10000),
+			},
+			wantErr: []bool{true, false},
+		},
+		{
+			name:     "profit withdrawal within reinvested profit",
+			existing: []models.Payout{deposit, payout(date(1, 31), models.KindReinvest, 1000)},
+			added:    []models.Payout{payout(date(2, 1), models.KindProfitWithdrawal, 1000)},
+			wantErr:  []bool{false},
+		},
+		{
+			name:     "profit withdrawal before the profit",
+			existing: []models.Payout{deposit, payout(date(3, 1), models.KindReinvest, 1000)},
+			added:    []models.Payout{payout(date(2, 1), models.KindProfitWithdrawal, 1000)},
+			wantErr:  []bool{true},
+		},
+		{
+			name: "backdated profit withdrawal overdraws a later one",
+			existing: []models.Payout{
+				deposit,
+				payout(date(1, 31), models.KindReinvest, 1000),
+				payout(date(3, 1), models.KindProfitWithdrawal, 1000),
+			},
+			added:   []models.Payout{payout(date(2, 1), models.KindProfitWithdrawal, 500)},
+			wantErr: []bool{true},
+		},
+		{
+			name:     "reversed later withdrawal is ignored",
+			existing: []models.Payout{deposit, {InvestorID: 1, PeriodDate: date(3, 1), Kind: models.KindCapitalWithdrawal, PayoutAmount: -10000, ReversedByID: new(int64)}},
+			added:    []models.Payout{payout(date(2, 1), models.KindCapitalWithdrawal, -10000)},
+			wantErr:  []bool{false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := CheckBackdated(inv, tt.existing, tt.added)
+			if len(errs) != len(tt.added) {
+				t.Fatalf("got %d results for %d operations", len(errs), len(tt.added))
+			}
+			for i, err := range errs {
+				if (err != nil) != tt.wantErr[i] {
+					t.Errorf("operation %d: err = %v, want error %v", i, err, tt.wantErr[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckAvailable(t *testing.T) {
+	b := Balance{InvestorID: 1, CapitalNow: 10000, NetProfit: 500}
+
+	tests := []struct {
+		name      string
+		kind      models.PayoutKind
+		amount    models.Money
+		available models.Money // 0 — операция допустима
+		wantErr   bool
+	}{
+		{name: "capital withdrawal within capital", kind: models.KindCapitalWithdrawal, amount: -10000},
+		{name: "capital withdrawal over capital", kind: models.KindCapitalWithdrawal, amount: -10001, available: 10000, wantErr: true},
+		{name: "capital withdrawal sign does not matter", kind: models.KindCapitalWithdrawal, amount: 10001, available: 10000, wantErr: true},
+
+		{name: "profit withdrawal within net profit", kind: models.KindProfitWithdrawal, amount: 500},
+		{name: "profit withdrawal sign does not matter", kind: models.KindProfitWithdrawal, amount: -500},
+		{name: "profit withdrawal over net profit", kind: models.KindProfitWithdrawal, amount: 501, available: 500, wantErr: true},
+
+		{name: "adjustment up", kind: models.KindAdjustment, amount: 1000000},
+		{name: "adjustment down within capital", kind: models.KindAdjustment, amount: -10000},
+		{name: "adjustment down over capital", kind: models.KindAdjustment, amount: -20000, available: 10000, wantErr: true},
+		{name: "negative deposit over capital", kind: models.KindDeposit, amount: -20000, available: 10000, wantErr: true},
+
+		{name: "topup", kind: models.KindTopup, amount: 5000},
+		{name: "negative topup over capital", kind: models.KindTopup, amount: -20000, available: 10000, wantErr: true},
+		{name: "negative reinvest over capital", kind: models.KindReinvest, amount: -20000, available: 10000, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckAvailable(b, models.Payout{InvestorID: 1, Kind: tt.kind, PayoutAmount: tt.amount})
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("CheckAvailable() = %v, want nil", err)
+				}
+				return
+			}
+
+			var ife *InsufficientFundsError
+			if !errors.As(err, &ife) {
+				t.Fatalf("CheckAvailable() = %v, want *InsufficientFundsError", err)
+			}
+			if ife.Available != tt.available || ife.Requested != tt.amount.Abs() {
+				t.Errorf("available %s, requested %s; want %s, %s",
+					ife.Available, ife.Requested, tt.available, tt.amount.Abs())
+			}
+		})
+	}
+}
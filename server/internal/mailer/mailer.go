@@ -0,0 +1,126 @@
+// Package mailer — отправка писем (сброс пароля и т.п.).
+// В разработке письма пишутся в лог или в файлы, в продакшене — через SMTP.
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"invest/internal/config"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string // обычный текст
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New выбирает реализацию по MAILER: log (по умолчанию), file или smtp.
+func New(cfg *config.Config) Mailer {
+	switch cfg.Mailer {
+	case "file":
+		log.Printf("Mailer: writing messages to %s", cfg.MailDir)
+		return &FileMailer{Dir: cfg.MailDir, From: cfg.MailFrom}
+	case "smtp":
+		log.Printf("Mailer: SMTP via %s", cfg.SMTPAddr)
+		return &SMTPMailer{
+			Addr:     cfg.SMTPAddr,
+			Username: cfg.SMTPUser,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		}
+	default:
+		log.Println("Mailer: messages are written to the log (MAILER=log)")
+		return LogMailer{}
+	}
+}
+
+// LogMailer печатает письма в лог — удобно локально.
+type LogMailer struct{}
+
+func (LogMailer) Send(_ context.Context, msg Message) error {
+	log.Printf("mail to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer сохраняет каждое письмо отдельным .eml файлом в Dir.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s_%s.eml",
+		time.Now().UTC().Format("20060102T150405.000000000"),
+		sanitize(msg.To))
+
+	return os.WriteFile(filepath.Join(m.Dir, name), compose(m.From, msg), 0o600)
+}
+
+// SMTPMailer — отправка через SMTP-сервер (STARTTLS, если сервер его поддерживает).
+type SMTPMailer struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(_ context.Context, msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	return smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, compose(m.From, msg))
+}
+
+func compose(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mimeHeader(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// mimeHeader кодирует тему письма (кириллица) по RFC 2047.
+func mimeHeader(s string) string {
+	for _, r := range s {
+		if r > 127 {
+			return mime.BEncoding.Encode("UTF-8", s)
+		}
+	}
+	return s
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuditEvent — запись журнала действий. Записи только добавляются и связаны
+// в цепочку: Hash считается от PrevHash и содержимого записи, поэтому
+// изменение или удаление любой записи ломает хеши всех следующих.
+type AuditEvent struct {
+	ID          int64           `json:"id"`
+	WorkspaceID int64           `json:"-"`
+	ActorID     *int64          `json:"actor_id,omitempty"`
+	Action      string          `json:"action"`
+	EntityType  string          `json:"entity_type"`
+	EntityID    *int64          `json:"entity_id,omitempty"`
+	Before      json.RawMessage `json:"before,omitempty"`
+	After       json.RawMessage `json:"after,omitempty"`
+	IP          string          `json:"ip"`
+	CreatedAt   time.Time       `json:"created_at"`
+	PrevHash    string          `json:"prev_hash"`
+	Hash        string          `json:"hash"`
+}
+
+// ComputeHash — sha256 от предыдущего хеша и полей записи (без ID —
+// он назначается базой). Время берётся в UTC с точностью Postgres (микросекунды).
+func (e *AuditEvent) ComputeHash() string {
+	var b strings.Builder
+
+	field := func(s string) {
+		b.WriteString(strconv.Itoa(len(s)))
+		b.WriteByte(':')
+		b.WriteString(s)
+		b.WriteByte('\n')
+	}
+	ref := func(id *int64) {
+		if id == nil {
+			field("")
+			return
+		}
+		field(strconv.FormatInt(*id, 10))
+	}
+
+	field(e.PrevHash)
+	field(strconv.FormatInt(e.WorkspaceID, 10))
+	ref(e.ActorID)
+	field(e.Action)
+	field(e.EntityType)
+	ref(e.EntityID)
+	field(string(e.Before))
+	field(string(e.After))
+	field(e.IP)
+	field(e.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano))
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditFilter — фильтры GET /api/audit. Нулевые значения не ограничивают выборку.
+type AuditFilter struct {
+	Action     string
+	EntityType string
+	EntityID   int64
+	ActorID    int64
+	From       *time.Time
+	To         *time.Time
+	BeforeID   int64 // пагинация: записи с id < BeforeID
+	Limit      int
+}
@@ -1,36 +1,278 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
 
 // ========================
 //       INVESTOR
 // ========================
 
 type Investor struct {
+	ID       int64  `json:"id"`
+	FullName string `json:"full_name"`
+
+	// вложено: сумма операций deposit и adjustment (в таблице не хранится)
+	InvestedAmount Money     `json:"invested_amount"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	// растёт при каждом изменении; отдаётся как ETag, проверяется по If-Match
+	Version int64 `json:"version"`
+
+	// текущая месячная ставка (null, если ещё не задана)
+	MonthlyPercent *Percent `json:"monthly_percent"`
+
+	Status     InvestorStatus `json:"status"`
+	ArchivedAt *time.Time     `json:"archived_at,omitempty"`
+	DeletedAt  *time.Time     `json:"deleted_at,omitempty"`
+}
+
+// InvestorStatus — инвесторы не удаляются физически, а архивируются
+// или помечаются удалёнными; история операций сохраняется.
+type InvestorStatus string
+
+const (
+	InvestorActive   InvestorStatus = "active"
+	InvestorArchived InvestorStatus = "archived"
+	InvestorDeleted  InvestorStatus = "deleted"
+)
+
+func (s InvestorStatus) Valid() bool {
+	switch s {
+	case InvestorActive, InvestorArchived, InvestorDeleted:
+		return true
+	}
+	return false
+}
+
+// ========================
+//     INVESTOR RATE
+// ========================
+
+// InvestorRate — месячный процент инвестора, действующий с EffectiveFrom.
+// Ставки не перезаписываются: каждая новая дата добавляет запись в историю.
+type InvestorRate struct {
 	ID             int64     `json:"id"`
-	FullName       string    `json:"full_name"`
-	InvestedAmount float64   `json:"invested_amount"`
+	InvestorID     int64     `json:"investor_id"`
+	MonthlyPercent Percent   `json:"monthly_percent"`
+	EffectiveFrom  time.Time `json:"effective_from"`
 	CreatedAt      time.Time `json:"created_at"`
 }
 
+// ========================
+//       PAYOUT KIND
+// ========================
+
+// PayoutKind — тип операции в таблице payouts (колонка kind).
+type PayoutKind string
+
+const (
+	KindReinvest          PayoutKind = "reinvest"
+	KindProfitWithdrawal  PayoutKind = "profit_withdrawal"
+	KindCapitalWithdrawal PayoutKind = "capital_withdrawal"
+	KindTopup             PayoutKind = "topup"
+
+	// KindDeposit — начальное вложение, KindAdjustment — последующее
+	// изменение вложенной суммы (со знаком). Создаются только через
+	// /api/investors и импорт, в слотах месяцев не показываются.
+	KindDeposit    PayoutKind = "deposit"
+	KindAdjustment PayoutKind = "adjustment"
+
+	// KindLegacy — старые строки, у которых не был выставлен ни один флаг.
+	// Через API такие операции не создаются и в расчётах не участвуют.
+	KindLegacy PayoutKind = "legacy"
+)
+
+var (
+	ErrInvalidPayoutKind = errors.New("exactly one payout kind must be set")
+	ErrNonPositiveAmount = errors.New("amount must be positive")
+)
+
+// Valid — можно ли создать операцию такого типа через API.
+func (k PayoutKind) Valid() bool {
+	switch k {
+	case KindReinvest, KindProfitWithdrawal, KindCapitalWithdrawal, KindTopup:
+		return true
+	}
+	return false
+}
+
+// Known — тип существует (в том числе служебные deposit / adjustment / legacy).
+func (k PayoutKind) Known() bool {
+	switch k {
+	case KindDeposit, KindAdjustment, KindLegacy:
+		return true
+	}
+	return k.Valid()
+}
+
+// Title — название типа операции для отчётов и импорта.
+func (k PayoutKind) Title() string {
+	switch k {
+	case KindDeposit:
+		return "Начальное вложение"
+	case KindAdjustment:
+		return "Изменение вложения"
+	case KindReinvest:
+		return "Реинвест"
+	case KindTopup:
+		return "Пополнение капитала"
+	case KindProfitWithdrawal:
+		return "Снятие прибыли"
+	case KindCapitalWithdrawal:
+		return "Снятие капитала"
+	}
+	return "Операция"
+}
+
+// KindFromFlags переводит старые булевы флаги в тип операции.
+// Должен быть выставлен ровно один флаг.
+func KindFromFlags(reinvest, withdrawalProfit, withdrawalCapital, topup bool) (PayoutKind, error) {
+	var kind PayoutKind
+	n := 0
+
+	if reinvest {
+		kind, n = KindReinvest, n+1
+	}
+	if withdrawalProfit {
+		kind, n = KindProfitWithdrawal, n+1
+	}
+	if withdrawalCapital {
+		kind, n = KindCapitalWithdrawal, n+1
+	}
+	if topup {
+		kind, n = KindTopup, n+1
+	}
+
+	if n != 1 {
+		return "", ErrInvalidPayoutKind
+	}
+	return kind, nil
+}
+
 // ========================
 //         PAYOUT
 // ========================
 
 type Payout struct {
-	ID                  int64      `json:"id"`
-	InvestorID          int64      `json:"investor_id"`
+	ID         int64 `json:"id"`
+	InvestorID int64 `json:"investor_id"`
 
 	// ⚠️ Оба поля поддерживаются, потому что часть старых данных может содержать period_month
-	PeriodMonth         *time.Time `json:"period_month,omitempty"` 
-	PeriodDate          *time.Time `json:"period_date,omitempty"`
+	PeriodMonth *time.Time `json:"period_month,omitempty"`
+	PeriodDate  *time.Time `json:"period_date,omitempty"`
+
+	PayoutAmount Money `json:"payout_amount"`
+
+	Kind PayoutKind `json:"kind"`
+
+	// пакетная выплата, в рамках которой создана операция
+	RunID *int64 `json:"run_id,omitempty"`
+
+	// сторно: ReversesID — у компенсирующей записи (ссылка на исходную),
+	// ReversedByID — у исходной записи, которую отменили
+	ReversesID   *int64 `json:"reverses_id,omitempty"`
+	ReversedByID *int64 `json:"reversed_by_id,omitempty"`
+	Reversed     bool   `json:"reversed"`
+
+	// исправление: новая запись, заменившая отменённую
+	ReplacesID *int64 `json:"replaces_id,omitempty"`
+
+	// пользователь, проведший операцию (у старых записей не заполнено)
+	CreatedBy *int64 `json:"created_by,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CheckAmount — сумма пополнения, реинвеста и снятий должна быть больше нуля.
+// Снятие капитала клиенты передают со знаком минус, поэтому у снятий
+// проверяется модуль. deposit / adjustment (со знаком) не проверяются.
+func (p Payout) CheckAmount() error {
+	switch p.Kind {
+	case KindTopup, KindReinvest:
+		if p.PayoutAmount <= 0 {
+			return ErrNonPositiveAmount
+		}
+	case KindProfitWithdrawal, KindCapitalWithdrawal:
+		if p.PayoutAmount.IsZero() {
+			return ErrNonPositiveAmount
+		}
+	}
+	return nil
+}
+
+// Voided — запись не влияет на баланс: либо её отменили, либо это сама
+// компенсирующая запись. Пара «исходная + сторно» взаимно погашается.
+func (p Payout) Voided() bool {
+	return p.ReversesID != nil || p.ReversedByID != nil
+}
+
+// MarshalJSON дополнительно отдаёт старые флаги (reinvest, is_topup, ...),
+// вычисленные из Kind, чтобы клиенты старых версий продолжали работать.
+func (p Payout) MarshalJSON() ([]byte, error) {
+	type payoutAlias Payout
+
+	return json.Marshal(struct {
+		payoutAlias
+		Reinvest            bool `json:"reinvest"`
+		IsWithdrawalProfit  bool `json:"is_withdrawal_profit"`
+		IsWithdrawalCapital bool `json:"is_withdrawal_capital"`
+		IsTopup             bool `json:"is_topup"`
+	}{
+		payoutAlias:         payoutAlias(p),
+		Reinvest:            p.Kind == KindReinvest,
+		IsWithdrawalProfit:  p.Kind == KindProfitWithdrawal,
+		IsWithdrawalCapital: p.Kind == KindCapitalWithdrawal,
+		IsTopup:             p.Kind == KindTopup,
+	})
+}
+
+// ========================
+//       PAYOUT RUN
+// ========================
+
+// RunStatus — состояние пакетной выплаты.
+type RunStatus string
+
+const (
+	RunDraft      RunStatus = "draft"       // предпросмотр, операции ещё не созданы
+	RunCommitted  RunStatus = "committed"   // операции записаны в payouts
+	RunRolledBack RunStatus = "rolled_back" // операции отменены
+)
+
+// PayoutRun — ежемесячная выплата по всем инвесторам одним пакетом.
+type PayoutRun struct {
+	ID           int64           `json:"id"`
+	WorkspaceID  int64           `json:"-"`
+	PeriodDate   time.Time       `json:"period_date"`
+	Status       RunStatus       `json:"status"`
+	Items        []PayoutRunItem `json:"items"`
+	Total        Money           `json:"total"`
+	CreatedAt    time.Time       `json:"created_at"`
+	CommittedAt  *time.Time      `json:"committed_at,omitempty"`
+	RolledBackAt *time.Time      `json:"rolled_back_at,omitempty"`
+
+	// заполнены, если пакет создан распределением общей прибыли фонда
+	PeriodFrom  *time.Time `json:"period_from,omitempty"`
+	TotalProfit *Money     `json:"total_profit,omitempty"`
+
+	CreatedBy *int64 `json:"created_by,omitempty"`
+}
 
-	PayoutAmount        float64    `json:"payout_amount"`
+// PayoutRunItem — строка пакетной выплаты по одному инвестору.
+type PayoutRunItem struct {
+	ID         int64      `json:"id"`
+	RunID      int64      `json:"run_id"`
+	InvestorID int64      `json:"investor_id"`
+	Kind       PayoutKind `json:"kind"`
+	Amount     Money      `json:"amount"`
 
-	Reinvest            bool       `json:"reinvest"`
-	IsWithdrawalProfit  bool       `json:"is_withdrawal_profit"`
-	IsWithdrawalCapital bool       `json:"is_withdrawal_capital"`
-	IsTopup             bool       `json:"is_topup"`
+	// база расчёта: капитал на дату и ставка (nil, если сумма задана вручную)
+	Capital        Money    `json:"capital"`
+	MonthlyPercent *Percent `json:"monthly_percent"`
 
-	CreatedAt           time.Time  `json:"created_at"`
+	// созданная операция (после commit)
+	PayoutID *int64 `json:"payout_id,omitempty"`
 }
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPayoutCheckAmount(t *testing.T) {
+	tests := []struct {
+		kind    PayoutKind
+		amount  Money
+		wantErr bool
+	}{
+		{kind: KindTopup, amount: 1},
+		{kind: KindTopup, amount: 0, wantErr: true},
+		{kind: KindTopup, amount: -100, wantErr: true},
+		{kind: KindReinvest, amount: 1},
+		{kind: KindReinvest, amount: 0, wantErr: true},
+		{kind: KindReinvest, amount: -100, wantErr: true},
+
+		// снятия передаются с любым знаком, ноль — нет
+		{kind: KindCapitalWithdrawal, amount: -100},
+		{kind: KindCapitalWithdrawal, amount: 100},
+		{kind: KindCapitalWithdrawal, amount: 0, wantErr: true},
+		{kind: KindProfitWithdrawal, amount: 100},
+		{kind: KindProfitWithdrawal, amount: -100},
+		{kind: KindProfitWithdrawal, amount: 0, wantErr: true},
+
+		{kind: KindAdjustment, amount: -100},
+		{kind: KindDeposit, amount: 0},
+	}
+
+	for _, tt := range tests {
+		err := Payout{Kind: tt.kind, PayoutAmount: tt.amount}.CheckAmount()
+		if tt.wantErr && !errors.Is(err, ErrNonPositiveAmount) {
+			t.Errorf("CheckAmount(%s, %s) = %v, want ErrNonPositiveAmount", tt.kind, tt.amount, err)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("CheckAmount(%s, %s) = %v, want nil", tt.kind, tt.amount, err)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ========================
+//         MONEY
+// ========================
+
+// Money — денежная сумма в копейках. Соответствует колонкам NUMERIC(18,2):
+// читается и пишется строкой без потерь, в JSON отдаётся строкой "1234.50".
+type Money int64
+
+const moneyScale = 100
+
+// ParseMoney разбирает десятичную строку ("1234.5", "-0.07") точно, без float64.
+// Лишние знаки после запятой округляются до копеек половиной от нуля — как NUMERIC(18,2).
+func ParseMoney(s string) (Money, error) {
+	r, err := parseDecimal(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid money value %q", s)
+	}
+	return MoneyFromRat(r)
+}
+
+// MoneyFromRat переводит точную дробь в копейки с округлением половиной от нуля.
+func MoneyFromRat(r *big.Rat) (Money, error) {
+	v, ok := roundScaled(r, moneyScale)
+	if !ok {
+		return 0, fmt.Errorf("money value out of range %s", r.FloatString(2))
+	}
+	return Money(v), nil
+}
+
+// parseDecimal — точный разбор десятичной строки: знак, цифры и дробная часть
+// через точку. Дроби вида "1/3" и экспонента ("1e3"), которые понимает
+// big.Rat, не принимаются.
+func parseDecimal(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	if !isDecimal(s) {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	return r, nil
+}
+
+func isDecimal(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	intPart, frac, _ := strings.Cut(s, ".")
+	if intPart == "" && frac == "" {
+		return false
+	}
+	for _, c := range intPart + frac {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// roundScaled возвращает r*scale, округлённое половиной от нуля.
+func roundScaled(r *big.Rat, scale int64) (int64, bool) {
+	scaled := new(big.Rat).Mul(r, big.NewRat(scale, 1))
+
+	num := new(big.Int).Abs(scaled.Num())
+	den := scaled.Denom()
+
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Lsh(rem, 1).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if scaled.Sign() < 0 {
+		q.Neg(q)
+	}
+
+	if !q.IsInt64() {
+		return 0, false
+	}
+	return q.Int64(), true
+}
+
+// String — "1234.50", "-0.07".
+func (m Money) String() string {
+	v := int64(m)
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/moneyScale, v%moneyScale)
+}
+
+// Float64 — только для отображения (PDF, Excel), не для расчётов.
+func (m Money) Float64() float64 {
+	return float64(m) / moneyScale
+}
+
+func (m Money) Add(o Money) Money { return m + o }
+func (m Money) Sub(o Money) Money { return m - o }
+func (m Money) Neg() Money        { return -m }
+func (m Money) IsZero() bool      { return m == 0 }
+
+// Rat — точное значение в рублях.
+func (m Money) Rat() *big.Rat {
+	return big.NewRat(int64(m), moneyScale)
+}
+
+// MulPercent — m × p / 100 с округлением до копеек.
+func (m Money) MulPercent(p Percent) Money {
+	r := new(big.Rat).Mul(m.Rat(), p.Rat())
+	r.Quo(r, big.NewRat(100, 1))
+
+	v, _ := roundScaled(r, moneyScale)
+	return Money(v)
+}
+
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// ------------------------
+//   database/sql
+// ------------------------
+
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		return m.parseInto(string(v))
+	case string:
+		return m.parseInto(v)
+	case int64:
+		*m = Money(v * moneyScale)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+}
+
+func (m *Money) parseInto(s string) error {
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value пишет сумму строкой — Postgres приводит её к NUMERIC без потерь.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// ------------------------
+//   JSON
+// ------------------------
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON принимает и строку ("1234.50"), и число (1234.5).
+// Число разбирается из исходного текста, без промежуточного float64.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	return m.parseInto(s)
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: "0", want: 0},
+		{in: "1234.5", want: 123450},
+		{in: "1234.50", want: 123450},
+		{in: " 12 ", want: 1200},
+		{in: "+5", want: 500},
+		{in: "-0.07", want: -7},
+		{in: ".5", want: 50},
+		{in: "1.", want: 100},
+
+		// округление половиной от нуля, как NUMERIC(18,2)
+		{in: "0.005", want: 1},
+		{in: "0.0049", want: 0},
+		{in: "-0.005", want: -1},
+		{in: "2.675", want: 268},
+
+		{in: "", wantErr: true},
+		{in: ".", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: "1e3", wantErr: true},
+		{in: "1E-2", wantErr: true},
+		{in: "1/3", wantErr: true},
+		{in: "1,5", wantErr: true},
+		{in: "1 000", wantErr: true},
+		{in: "--1", wantErr: true},
+		{in: "Inf", wantErr: true},
+		{in: "NaN", wantErr: true},
+		{in: "0x10", wantErr: true},
+		{in: "100000000000000000000", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseMoney(%q) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseMoney(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{0, "0.00"},
+		{7, "0.07"},
+		{-7, "-0.07"},
+		{123450, "1234.50"},
+		{-100, "-1.00"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: `"1234.50"`, want: 123450},
+		{in: `1234.5`, want: 123450},
+		{in: `0.1`, want: 10},
+		{in: `-3`, want: -300},
+		{in: `null`, want: 0},
+		{in: `1e3`, wantErr: true},
+		{in: `"abc"`, wantErr: true},
+		{in: `true`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var got Money
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Unmarshal(%s) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyMulPercent(t *testing.T) {
+	tests := []struct {
+		m    Money
+		p    string
+		want Money
+	}{
+		{m: 10000000, p: "5", want: 500000},
+		{m: 10000000, p: "5.5", want: 550000},
+		{m: 333, p: "50", want: 167},   // 1.665 → 1.67
+		{m: -333, p: "50", want: -167}, // половиной от нуля
+		{m: 100, p: "0.0125", want: 0},
+		{m: 0, p: "12", want: 0},
+	}
+	for _, tt := range tests {
+		p, err := ParsePercent(tt.p)
+		if err != nil {
+			t.Fatalf("ParsePercent(%q): %v", tt.p, err)
+		}
+		if got := tt.m.MulPercent(p); got != tt.want {
+			t.Errorf("Money(%d).MulPercent(%s) = %d, want %d", tt.m, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Percent
+		str     string
+		wantErr bool
+	}{
+		{in: "5.5", want: 55000, str: "5.5"},
+		{in: "12", want: 120000, str: "12"},
+		{in: "0.0125", want: 125, str: "0.0125"},
+		{in: "0.00005", want: 1, str: "0.0001"},
+		{in: "-1.5", want: -15000, str: "-1.5"},
+		{in: "5e1", wantErr: true},
+		{in: "1/2", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePercent(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParsePercent(%q) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsePercent(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want || got.String() != tt.str {
+			t.Errorf("ParsePercent(%q) = %d (%s), want %d (%s)", tt.in, got, got, tt.want, tt.str)
+		}
+	}
+}
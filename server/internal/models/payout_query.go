@@ -0,0 +1,89 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// PayoutSort — порядок выдачи GET /api/payouts. Внутри одинаковых значений
+// операции упорядочены по id в том же направлении.
+type PayoutSort string
+
+const (
+	SortDateAsc    PayoutSort = "date"
+	SortDateDesc   PayoutSort = "-date"
+	SortAmountAsc  PayoutSort = "amount"
+	SortAmountDesc PayoutSort = "-amount"
+)
+
+func (s PayoutSort) Valid() bool {
+	switch s {
+	case SortDateAsc, SortDateDesc, SortAmountAsc, SortAmountDesc:
+		return true
+	}
+	return false
+}
+
+// Desc — сортировка по убыванию.
+func (s PayoutSort) Desc() bool {
+	return s == SortDateDesc || s == SortAmountDesc
+}
+
+// ByAmount — сортировка по сумме (иначе по дате).
+func (s PayoutSort) ByAmount() bool {
+	return s == SortAmountAsc || s == SortAmountDesc
+}
+
+// PayoutFilter — фильтры GET /api/payouts. Нулевые значения не ограничивают выборку.
+type PayoutFilter struct {
+	InvestorID int64
+	From       *time.Time // period_date >= From
+	To         *time.Time // period_date <= To
+	Kinds      []PayoutKind
+
+	// границы суммы со знаком (снятия хранятся отрицательными)
+	MinAmount *Money
+	MaxAmount *Money
+
+	Sort  PayoutSort
+	After *PayoutCursor // пагинация: операции после этой позиции
+	Limit int
+}
+
+// PayoutCursor — позиция последней выданной операции в выбранном порядке.
+// У старых операций дата может быть пустой (Date == nil).
+type PayoutCursor struct {
+	Sort   PayoutSort `json:"s"`
+	Date   *time.Time `json:"d,omitempty"`
+	Amount Money      `json:"a"`
+	ID     int64      `json:"id"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorAfter — курсор, указывающий на операцию p.
+func CursorAfter(p Payout, sort PayoutSort) PayoutCursor {
+	return PayoutCursor{Sort: sort, Date: p.PeriodDate, Amount: p.PayoutAmount, ID: p.ID}
+}
+
+// Encode — непрозрачная строка для параметра cursor.
+func (c PayoutCursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParsePayoutCursor разбирает cursor и проверяет, что он выдан для того же порядка.
+func ParsePayoutCursor(s string, sort PayoutSort) (*PayoutCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c PayoutCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID <= 0 || c.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ========================
+//        PERCENT
+// ========================
+
+// Percent — процент с точностью до 4 знаков (колонка NUMERIC(9,4)).
+// Хранится в десятитысячных долях процента: 5.5% = 55000.
+type Percent int64
+
+const percentScale = 10000
+
+// MaxPercent — наибольшее значение, которое помещается в NUMERIC(9,4).
+const MaxPercent Percent = 99999_9999
+
+func ParsePercent(s string) (Percent, error) {
+	r, err := parseDecimal(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percent value %q", s)
+	}
+
+	v, ok := roundScaled(r, percentScale)
+	if !ok {
+		return 0, fmt.Errorf("percent value out of range %q", s)
+	}
+	return Percent(v), nil
+}
+
+// String — "5.5", "12", "0.0125" (без лишних нулей).
+func (p Percent) String() string {
+	return strings.TrimSuffix(strings.TrimRight(p.Rat().FloatString(4), "0"), ".")
+}
+
+// Rat — точное значение в процентах.
+func (p Percent) Rat() *big.Rat {
+	return big.NewRat(int64(p), percentScale)
+}
+
+// ------------------------
+//   database/sql
+// ------------------------
+
+func (p *Percent) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*p = 0
+		return nil
+	case []byte:
+		return p.parseInto(string(v))
+	case string:
+		return p.parseInto(v)
+	case int64:
+		*p = Percent(v * percentScale)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Percent", src)
+	}
+}
+
+func (p *Percent) parseInto(s string) error {
+	v, err := ParsePercent(s)
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
+
+func (p Percent) Value() (driver.Value, error) {
+	return p.String(), nil
+}
+
+// ------------------------
+//   JSON
+// ------------------------
+
+func (p Percent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// UnmarshalJSON принимает и строку ("5.5"), и число (5.5).
+func (p *Percent) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	return p.parseInto(s)
+}
@@ -6,5 +6,85 @@ type User struct {
 	ID           int64     `json:"id"`
 	Email        string    `json:"email"`
 	PasswordHash string    `json:"-"`
+	WorkspaceID  int64     `json:"workspace_id"`
+	Role         Role      `json:"role"`
+	TOTPEnabled  bool      `json:"totp_enabled"`
 	CreatedAt    time.Time `json:"created_at"`
+
+	// политика пространства: без включённой 2FA доступна только её настройка
+	TwoFactorRequired bool `json:"-"`
+
+	// неудачные попытки входа подряд и блокировка после них
+	FailedLogins int        `json:"-"`
+	LockedUntil  *time.Time `json:"-"`
+}
+
+// LockedAt — вход временно заблокирован после неудачных попыток.
+func (u *User) LockedAt(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// NeedsTwoFactorSetup — пространство требует 2FA, а пользователь её ещё не включил.
+func (u *User) NeedsTwoFactorSetup() bool {
+	return u.TwoFactorRequired && !u.TOTPEnabled
+}
+
+// TwoFactor — состояние 2FA пользователя. Секреты наружу не отдаются.
+type TwoFactor struct {
+	Enabled           bool   `json:"enabled"`
+	Required          bool   `json:"required"`
+	RecoveryCodesLeft int    `json:"recovery_codes_left"`
+	Secret            string `json:"-"`
+	PendingSecret     string `json:"-"`
+}
+
+// Invite — одноразовое приглашение в пространство на конкретный email и роль.
+// Сам токен отдаётся только при создании, в базе хранится его хеш.
+type Invite struct {
+	ID          int64      `json:"id"`
+	WorkspaceID int64      `json:"-"`
+	Email       string     `json:"email"`
+	Role        Role       `json:"role"`
+	CreatedBy   int64      `json:"created_by"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Role — права пользователя внутри рабочего пространства.
+type Role string
+
+const (
+	RoleViewer     Role = "viewer"     // только чтение
+	RoleAccountant Role = "accountant" // операции и инвесторы, без удаления
+	RoleOwner      Role = "owner"      // всё, включая управление пользователями
+)
+
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleAccountant:
+		return 2
+	case RoleOwner:
+		return 3
+	}
+	return 0
+}
+
+func (r Role) Valid() bool {
+	return r.rank() > 0
+}
+
+// Allows — роль не ниже min.
+func (r Role) Allows(min Role) bool {
+	return r.Valid() && r.rank() >= min.rank()
+}
+
+// Workspace — организация: инвесторы и операции видны только её пользователям.
+type Workspace struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Require2FA bool      `json:"require_2fa"`
+	CreatedAt  time.Time `json:"created_at"`
 }
@@ -0,0 +1,285 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Шрифты лежат рядом с пакетом (копия client/public/fonts), чтобы
+// вид выписки не зависел ни от браузера, ни от шрифтов на сервере.
+//
+//go:embed fonts/Roboto-Regular.ttf fonts/Roboto-Bold.ttf
+var fontFiles embed.FS
+
+// Style — начертание шрифта.
+type Style int
+
+const (
+	Regular Style = iota
+	Bold
+)
+
+var fontNames = [...]string{
+	Regular: "Roboto-Regular",
+	Bold:    "Roboto-Bold",
+}
+
+// face — разобранный TrueType-шрифт. Общий для всех документов,
+// после загрузки не меняется.
+type face struct {
+	name string
+
+	unitsPerEm int
+	ascent     int
+	descent    int
+	capHeight  int
+	bbox       [4]int
+
+	advances []uint16        // ширины глифов в единицах шрифта
+	glyphs   map[rune]uint16 // cmap: символ → глиф
+
+	size       int    // размер исходного файла (Length1)
+	compressed []byte // файл, сжатый Flate, — для FontFile2
+}
+
+var (
+	facesOnce sync.Once
+	faces     [len(fontNames)]*face
+	facesErr  error
+)
+
+// loadFaces разбирает встроенные шрифты один раз на процесс.
+func loadFaces() ([len(fontNames)]*face, error) {
+	facesOnce.Do(func() {
+		for st, name := range fontNames {
+			data, err := fontFiles.ReadFile("fonts/" + name + ".ttf")
+			if err != nil {
+				facesErr = err
+				return
+			}
+			f, err := parseTTF(name, data)
+			if err != nil {
+				facesErr = fmt.Errorf("font %s: %w", name, err)
+				return
+			}
+			faces[st] = f
+		}
+	})
+	return faces, facesErr
+}
+
+// glyph — глиф символа; 0 (.notdef), если символа в шрифте нет.
+func (f *face) glyph(r rune) uint16 { return f.glyphs[r] }
+
+// width — ширина глифа в тысячных долях кегля.
+func (f *face) width(gid uint16) float64 {
+	if len(f.advances) == 0 {
+		return 0
+	}
+	if int(gid) >= len(f.advances) {
+		gid = uint16(len(f.advances) - 1)
+	}
+	return f.scale(int(f.advances[gid]))
+}
+
+// scale переводит единицы шрифта в тысячные доли кегля (единицы PDF).
+func (f *face) scale(v int) float64 { return float64(v) * 1000 / float64(f.unitsPerEm) }
+
+// ========================
+//     РАЗБОР TRUETYPE
+// ========================
+
+var errBadFont = errors.New("malformed TrueType font")
+
+// parseTTF читает из файла только то, что нужно для встраивания:
+// метрики (head, hhea, OS/2), ширины (hmtx) и таблицу символов (cmap).
+func parseTTF(name string, data []byte) (*face, error) {
+	tables, err := ttfTables(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range []string{"head", "hhea", "hmtx", "cmap"} {
+		if tables[tag] == nil {
+			return nil, fmt.Errorf("%w: no %s table", errBadFont, tag)
+		}
+	}
+
+	f := &face{name: name, size: len(data)}
+
+	head := tables["head"]
+	if len(head) < 54 {
+		return nil, errBadFont
+	}
+	f.unitsPerEm = int(u16(head, 18))
+	if f.unitsPerEm == 0 {
+		return nil, errBadFont
+	}
+	for i := range f.bbox {
+		f.bbox[i] = int(int16(u16(head, 36+2*i)))
+	}
+
+	hhea := tables["hhea"]
+	if len(hhea) < 36 {
+		return nil, errBadFont
+	}
+	f.ascent = int(int16(u16(hhea, 4)))
+	f.descent = int(int16(u16(hhea, 6)))
+	f.capHeight = f.ascent
+	if os2 := tables["OS/2"]; len(os2) >= 90 && u16(os2, 0) >= 2 {
+		f.capHeight = int(int16(u16(os2, 88)))
+	}
+
+	// hmtx: numberOfHMetrics пар (ширина, отступ); у остальных глифов
+	// ширина последней пары
+	hmtx := tables["hmtx"]
+	n := int(u16(hhea, 34))
+	if n == 0 || len(hmtx) < 4*n {
+		return nil, errBadFont
+	}
+	f.advances = make([]uint16, n)
+	for i := range f.advances {
+		f.advances[i] = u16(hmtx, 4*i)
+	}
+
+	if f.glyphs, err = parseCmap(tables["cmap"]); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw, _ := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	f.compressed = buf.Bytes()
+
+	return f, nil
+}
+
+func ttfTables(data []byte) (map[string][]byte, error) {
+	if len(data) < 12 {
+		return nil, errBadFont
+	}
+	n := int(u16(data, 4))
+	if len(data) < 12+16*n {
+		return nil, errBadFont
+	}
+
+	tables := make(map[string][]byte, n)
+	for i := 0; i < n; i++ {
+		rec := data[12+16*i:]
+		off, length := int(u32(rec, 8)), int(u32(rec, 12))
+		if off < 0 || length < 0 || off+length > len(data) {
+			return nil, errBadFont
+		}
+		tables[string(rec[:4])] = data[off : off+length]
+	}
+	return tables, nil
+}
+
+// parseCmap берёт юникодную подтаблицу: формат 12 (вся плоскость)
+// или формат 4 (BMP).
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	if len(cmap) < 4 {
+		return nil, errBadFont
+	}
+
+	var fmt4, fmt12 []byte
+	n := int(u16(cmap, 2))
+	for i := 0; i < n && 4+8*i+8 <= len(cmap); i++ {
+		platform, encoding := u16(cmap, 4+8*i), u16(cmap, 6+8*i)
+		off := int(u32(cmap, 8+8*i))
+		if off+4 > len(cmap) {
+			continue
+		}
+		unicode := platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))
+		if !unicode {
+			continue
+		}
+		switch u16(cmap, off) {
+		case 4:
+			fmt4 = cmap[off:]
+		case 12:
+			fmt12 = cmap[off:]
+		}
+	}
+
+	switch {
+	case fmt12 != nil:
+		return parseCmap12(fmt12)
+	case fmt4 != nil:
+		return parseCmap4(fmt4)
+	}
+	return nil, fmt.Errorf("%w: no unicode cmap", errBadFont)
+}
+
+func parseCmap4(t []byte) (map[rune]uint16, error) {
+	if len(t) < 14 {
+		return nil, errBadFont
+	}
+	segs := int(u16(t, 6)) / 2
+	endOff := 14
+	startOff := endOff + 2*segs + 2
+	deltaOff := startOff + 2*segs
+	rangeOff := deltaOff + 2*segs
+	if len(t) < rangeOff+2*segs {
+		return nil, errBadFont
+	}
+
+	glyphs := make(map[rune]uint16)
+	for i := 0; i < segs; i++ {
+		end, start := u16(t, endOff+2*i), u16(t, startOff+2*i)
+		delta, ro := u16(t, deltaOff+2*i), u16(t, rangeOff+2*i)
+		for c := uint32(start); c <= uint32(end) && c != 0xFFFF; c++ {
+			var g uint16
+			if ro == 0 {
+				g = uint16(c) + delta
+			} else {
+				// смещение отсчитывается от самого элемента idRangeOffset
+				addr := rangeOff + 2*i + int(ro) + 2*int(c-uint32(start))
+				if addr+2 > len(t) {
+					continue
+				}
+				if g = u16(t, addr); g != 0 {
+					g += delta
+				}
+			}
+			if g != 0 {
+				glyphs[rune(c)] = g
+			}
+		}
+	}
+	return glyphs, nil
+}
+
+func parseCmap12(t []byte) (map[rune]uint16, error) {
+	if len(t) < 16 {
+		return nil, errBadFont
+	}
+	n := int(u32(t, 12))
+	if len(t) < 16+12*n {
+		return nil, errBadFont
+	}
+
+	glyphs := make(map[rune]uint16)
+	for i := 0; i < n; i++ {
+		start, end, g := u32(t, 16+12*i), u32(t, 20+12*i), u32(t, 24+12*i)
+		if end < start || end > 0x10FFFF {
+			return nil, errBadFont
+		}
+		for c := start; c <= end; c++ {
+			glyphs[rune(c)] = uint16(g + c - start)
+		}
+	}
+	return glyphs, nil
+}
+
+func u16(b []byte, off int) uint16 { return binary.BigEndian.Uint16(b[off:]) }
+func u32(b []byte, off int) uint32 { return binary.BigEndian.Uint32(b[off:]) }
@@ -0,0 +1,329 @@
+// Package pdf — минимальная запись PDF без внешних зависимостей: страницы
+// A4, текст встроенным шрифтом с кириллицей, заливка прямоугольников и
+// линии. Координаты — в пунктах от левого верхнего угла страницы.
+//
+// Вывод детерминирован: одинаковые вызовы дают побайтно одинаковый файл.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// размеры A4 в пунктах
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Color — цвет RGB, компоненты 0..255.
+type Color struct{ R, G, B uint8 }
+
+var (
+	Black = Color{0, 0, 0}
+	White = Color{255, 255, 255}
+)
+
+type page struct {
+	content bytes.Buffer
+}
+
+// Document — PDF-документ. Рисование идёт на последнюю добавленную
+// страницу (или выбранную через SetPage).
+type Document struct {
+	faces [len(fontNames)]*face
+	pages []*page
+	page  int // текущая страница
+
+	style    Style
+	size     float64
+	text     Color
+	fill     Color
+	lineW    float64
+	usedFace [len(fontNames)]map[uint16]rune // глиф → символ (для ToUnicode)
+}
+
+// New создаёт пустой документ со шрифтом Regular 10 pt.
+func New() (*Document, error) {
+	faces, err := loadFaces()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Document{faces: faces, size: 10, lineW: 0.5}
+	for i := range d.usedFace {
+		d.usedFace[i] = make(map[uint16]rune)
+	}
+	return d, nil
+}
+
+// AddPage начинает новую страницу.
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &page{})
+	d.page = len(d.pages) - 1
+}
+
+// PageCount — число страниц.
+func (d *Document) PageCount() int { return len(d.pages) }
+
+// SetPage переключает рисование на страницу n (с 1), например чтобы
+// проставить номера страниц в конце.
+func (d *Document) SetPage(n int) {
+	if n < 1 || n > len(d.pages) {
+		return
+	}
+	d.page = n - 1
+}
+
+// SetFont задаёт начертание и кегль для следующих Text.
+func (d *Document) SetFont(style Style, size float64) {
+	d.style, d.size = style, size
+}
+
+// SetTextColor задаёт цвет текста.
+func (d *Document) SetTextColor(c Color) { d.text = c }
+
+// SetFillColor задаёт цвет заливки для FillRect.
+func (d *Document) SetFillColor(c Color) { d.fill = c }
+
+// SetLineWidth задаёт толщину линий в пунктах.
+func (d *Document) SetLineWidth(w float64) { d.lineW = w }
+
+// TextWidth — ширина строки текущим шрифтом в пунктах.
+func (d *Document) TextWidth(s string) float64 {
+	f := d.faces[d.style]
+	var w float64
+	for _, r := range s {
+		w += f.width(f.glyph(r))
+	}
+	return w * d.size / 1000
+}
+
+// Text выводит строку; (x, y) — начало базовой линии.
+func (d *Document) Text(x, y float64, s string) {
+	f := d.faces[d.style]
+	used := d.usedFace[d.style]
+
+	var hex strings.Builder
+	for _, r := range s {
+		g := f.glyph(r)
+		if _, ok := used[g]; !ok {
+			used[g] = r
+		}
+		fmt.Fprintf(&hex, "%04X", g)
+	}
+
+	c := d.cur()
+	fmt.Fprintf(c, "BT /F%d %s Tf %s rg %s %s Td <%s> Tj ET\n",
+		d.style+1, num(d.size), rgb(d.text), num(x), num(PageHeight-y), hex.String())
+}
+
+// TextRight выводит строку, выровненную по правому краю right.
+func (d *Document) TextRight(right, y float64, s string) {
+	d.Text(right-d.TextWidth(s), y, s)
+}
+
+// FillRect заливает прямоугольник; (x, y) — левый верхний угол.
+func (d *Document) FillRect(x, y, w, h float64) {
+	fmt.Fprintf(d.cur(), "%s rg %s %s %s %s re f\n",
+		rgb(d.fill), num(x), num(PageHeight-y-h), num(w), num(h))
+}
+
+// Line рисует линию цветом текста.
+func (d *Document) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.cur(), "%s w %s RG %s %s m %s %s l S\n",
+		num(d.lineW), rgb(d.text), num(x1), num(PageHeight-y1), num(x2), num(PageHeight-y2))
+}
+
+func (d *Document) cur() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return &d.pages[d.page].content
+}
+
+// num — число для потока PDF: не больше двух знаков после точки, без хвостовых нулей.
+func num(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "" || s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+func rgb(c Color) string {
+	return num(float64(c.R)/255) + " " + num(float64(c.G)/255) + " " + num(float64(c.B)/255)
+}
+
+// ========================
+//         ЗАПИСЬ
+// ========================
+
+// writer собирает объекты PDF и таблицу xref.
+type writer struct {
+	buf     bytes.Buffer
+	offsets []int // смещение объекта i+1
+}
+
+// reserve выделяет номер объекта, который будет записан позже.
+func (w *writer) reserve() int {
+	w.offsets = append(w.offsets, 0)
+	return len(w.offsets)
+}
+
+func (w *writer) object(id int, body string) {
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+// stream пишет поток, сжатый Flate.
+func (w *writer) stream(id int, data []byte) error {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	w.rawStream(id, z.Bytes(), "/Filter /FlateDecode")
+	return nil
+}
+
+func (w *writer) rawStream(id int, data []byte, dict string) {
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< /Length %d %s >>\nstream\n", id, len(data), dict)
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+// Write пишет документ. Встраиваются только использованные начертания.
+func (d *Document) Write(out io.Writer) error {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	w := &writer{}
+	w.buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	catalog := w.reserve()
+	pagesID := w.reserve()
+
+	var fonts strings.Builder
+	for st := range d.faces {
+		if len(d.usedFace[st]) == 0 {
+			continue
+		}
+		id, err := d.writeFont(w, Style(st))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&fonts, "/F%d %d 0 R ", st+1, id)
+	}
+
+	kids := make([]string, 0, len(d.pages))
+	for _, p := range d.pages {
+		contentID := w.reserve()
+		if err := w.stream(contentID, p.content.Bytes()); err != nil {
+			return err
+		}
+		pageID := w.reserve()
+		w.object(pageID, fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /Font << %s>> >> /Contents %d 0 R >>",
+			pagesID, num(PageWidth), num(PageHeight), fonts.String(), contentID))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageID))
+	}
+
+	w.object(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	w.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, off := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, catalog, xref)
+
+	_, err := w.buf.WriteTo(out)
+	return err
+}
+
+// writeFont встраивает шрифт как Type0 / CIDFontType2 с кодировкой
+// Identity-H: коды в тексте — номера глифов. Возвращает номер объекта Type0.
+func (d *Document) writeFont(w *writer, st Style) (int, error) {
+	f := d.faces[st]
+	used := d.usedFace[st]
+
+	gids := make([]int, 0, len(used))
+	for g := range used {
+		gids = append(gids, int(g))
+	}
+	sort.Ints(gids)
+
+	fileID := w.reserve()
+	w.rawStream(fileID, f.compressed, fmt.Sprintf("/Length1 %d /Filter /FlateDecode", f.size))
+
+	descID := w.reserve()
+	w.object(descID, fmt.Sprintf(
+		"<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%s %s %s %s] /ItalicAngle 0 /Ascent %s /Descent %s /CapHeight %s /StemV 80 /FontFile2 %d 0 R >>",
+		f.name, num(f.scale(f.bbox[0])), num(f.scale(f.bbox[1])), num(f.scale(f.bbox[2])), num(f.scale(f.bbox[3])),
+		num(f.scale(f.ascent)), num(f.scale(f.descent)), num(f.scale(f.capHeight)), fileID))
+
+	var widths strings.Builder
+	for _, g := range gids {
+		fmt.Fprintf(&widths, "%d [%s] ", g, num(f.width(uint16(g))))
+	}
+
+	cidID := w.reserve()
+	w.object(cidID, fmt.Sprintf(
+		"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /CIDToGIDMap /Identity /W [%s] >>",
+		f.name, descID, widths.String()))
+
+	// ToUnicode — чтобы текст из PDF можно было копировать и искать
+	var cmap strings.Builder
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	cmap.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	cmap.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	cmap.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for i := 0; i < len(gids); i += 100 {
+		chunk := gids[i:min(i+100, len(gids))]
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", len(chunk))
+		for _, g := range chunk {
+			// .notdef стоит вместо символов, которых нет в шрифте, — их не отображаем
+			if g == 0 {
+				fmt.Fprintf(&cmap, "<0000> <FFFD>\n")
+				continue
+			}
+			fmt.Fprintf(&cmap, "<%04X> <%s>\n", g, utf16Hex(used[uint16(g)]))
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+
+	toUnicodeID := w.reserve()
+	if err := w.stream(toUnicodeID, []byte(cmap.String())); err != nil {
+		return 0, err
+	}
+
+	fontID := w.reserve()
+	w.object(fontID, fmt.Sprintf(
+		"<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		f.name, cidID, toUnicodeID))
+
+	return fontID, nil
+}
+
+// utf16Hex — символ в UTF-16BE шестнадцатеричной записью (с суррогатами).
+func utf16Hex(r rune) string {
+	if r < 0x10000 {
+		return fmt.Sprintf("%04X", r)
+	}
+	r -= 0x10000
+	return fmt.Sprintf("%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"invest/internal/models"
+	"strings"
+	"time"
+)
+
+//
+// ========================
+//      AUDIT
+// ========================
+//
+
+// auditLockKey — первый ключ advisory lock'а цепочки журнала (второй — пространство).
+const auditLockKey = 1017
+
+// AppendAudit добавляет запись в конец цепочки пространства.
+// Цепочка блокируется advisory lock'ом, чтобы параллельные записи не получили
+// один и тот же prev_hash. Внутри InTx запись идёт в той же транзакции, что
+// и изменение, и lock держится до её конца — поэтому журнал пишется
+// последним шагом транзакции.
+func (r *Repository) AppendAudit(ctx context.Context, e *models.AuditEvent) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx,
+			`SELECT pg_advisory_xact_lock($1, $2)`, auditLockKey, e.WorkspaceID); err != nil {
+			return err
+		}
+
+		err := tx.QueryRowContext(ctx,
+			`SELECT hash FROM audit_events
+			 WHERE workspace_id=$1
+			 ORDER BY id DESC
+			 LIMIT 1`,
+			e.WorkspaceID,
+		).Scan(&e.PrevHash)
+		if err == sql.ErrNoRows {
+			e.PrevHash = ""
+		} else if err != nil {
+			return err
+		}
+
+		e.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		e.Hash = e.ComputeHash()
+
+		return tx.QueryRowContext(ctx,
+			`INSERT INTO audit_events
+			     (workspace_id, actor_id, action, entity_type, entity_id, before, after, ip, created_at, prev_hash, hash)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			 RETURNING id`,
+			e.WorkspaceID, e.ActorID, e.Action, e.EntityType, e.EntityID,
+			jsonArg(e.Before), jsonArg(e.After), e.IP, e.CreatedAt, e.PrevHash, e.Hash,
+		).Scan(&e.ID)
+	})
+}
+
+// jsonArg — пустой JSON пишется как NULL.
+func jsonArg(b []byte) any {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}
+
+const auditSelect = `SELECT id, workspace_id, actor_id, action, entity_type, entity_id,
+                before, after, ip, created_at, prev_hash, hash
+         FROM audit_events`
+
+// ListAudit — записи пространства по фильтру, новые сверху.
+func (r *Repository) ListAudit(ctx context.Context, workspaceID int64, f models.AuditFilter) ([]models.AuditEvent, error) {
+	where := []string{"workspace_id=$1"}
+	args := []any{workspaceID}
+
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.Action != "" {
+		add("action=$%d", f.Action)
+	}
+	if f.EntityType != "" {
+		add("entity_type=$%d", f.EntityType)
+	}
+	if f.EntityID != 0 {
+		add("entity_id=$%d", f.EntityID)
+	}
+	if f.ActorID != 0 {
+		add("actor_id=$%d", f.ActorID)
+	}
+	if f.From != nil {
+		add("created_at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("created_at < $%d", *f.To)
+	}
+	if f.BeforeID != 0 {
+		add("id < $%d", f.BeforeID)
+	}
+
+	args = append(args, f.Limit)
+	query := auditSelect + `
+         WHERE ` + strings.Join(where, " AND ") + fmt.Sprintf(`
+         ORDER BY id DESC
+         LIMIT $%d`, len(args))
+
+	rows, err := r.q(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAudit(rows)
+}
+
+// AuditVerification — результат проверки цепочки.
+type AuditVerification struct {
+	OK       bool   `json:"ok"`
+	Checked  int    `json:"checked"`
+	BrokenAt *int64 `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// VerifyAudit пересчитывает хеши всей цепочки пространства по порядку.
+func (r *Repository) VerifyAudit(ctx context.Context, workspaceID int64) (*AuditVerification, error) {
+	rows, err := r.q(ctx).QueryContext(ctx,
+		auditSelect+`
+         WHERE workspace_id=$1
+         ORDER BY id`,
+		workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list, err := scanAudit(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &AuditVerification{OK: true}
+	prev := ""
+	for i := range list {
+		e := &list[i]
+		switch {
+		case e.PrevHash != prev:
+			res.Reason = "prev_hash does not match previous entry (entry removed or reordered)"
+		case e.ComputeHash() != e.Hash:
+			res.Reason = "hash does not match entry contents (entry modified)"
+		}
+		if res.Reason != "" {
+			res.OK = false
+			res.BrokenAt = &e.ID
+			return res, nil
+		}
+		prev = e.Hash
+		res.Checked++
+	}
+	return res, nil
+}
+
+func scanAudit(rows *sql.Rows) ([]models.AuditEvent, error) {
+	var out []models.AuditEvent
+	for rows.Next() {
+		var (
+			e             models.AuditEvent
+			before, after sql.NullString
+		)
+		if err := rows.Scan(
+			&e.ID, &e.WorkspaceID, &e.ActorID, &e.Action, &e.EntityType, &e.EntityID,
+			&before, &after, &e.IP, &e.CreatedAt, &e.PrevHash, &e.Hash,
+		); err != nil {
+			return nil, err
+		}
+		if before.Valid {
+			e.Before = []byte(before.String)
+		}
+		if after.Valid {
+			e.After = []byte(after.String)
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+//
+// ========================
+//    IDEMPOTENCY KEYS
+// ========================
+//
+
+var (
+	// ErrIdempotencyMismatch — ключ уже использован с другим запросом.
+	ErrIdempotencyMismatch = errors.New("idempotency key reused with a different request")
+
+	// ErrIdempotencyInProgress — первый запрос с этим ключом ещё выполняется.
+	ErrIdempotencyInProgress = errors.New("request with this idempotency key is in progress")
+)
+
+// IdempotentResponse — сохранённый ответ на запрос с Idempotency-Key.
+type IdempotentResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// ClaimIdempotencyKey резервирует ключ за запросом с хешем requestHash.
+// Возвращает nil, если ключ новый (запрос нужно выполнить и затем вызвать
+// CompleteIdempotencyKey), или сохранённый ответ, если запрос уже выполнен.
+// Ключи старше ttl забываются и могут использоваться заново. Незавершённый
+// резерв старше lease считается брошенным (процесс упал посреди запроса)
+// и тоже занимается заново.
+func (r *Repository) ClaimIdempotencyKey(ctx context.Context, userID int64, key, requestHash string, ttl, lease time.Duration) (*IdempotentResponse, error) {
+	var resp *IdempotentResponse
+
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		now := time.Now()
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM idempotency_keys
+			 WHERE user_id=$1 AND key=$2
+			   AND (created_at < $3 OR (status_code IS NULL AND created_at < $4))`,
+			userID, key, now.Add(-ttl), now.Add(-lease)); err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO idempotency_keys (user_id, key, request_hash)
+			 VALUES ($1, $2, $3)
+			 ON CONFLICT (user_id, key) DO NOTHING`,
+			userID, key, requestHash)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 1 {
+			return nil // ключ новый
+		}
+
+		var (
+			hash        string
+			status      sql.NullInt64
+			contentType sql.NullString
+			body        []byte
+		)
+		err = tx.QueryRowContext(ctx,
+			`SELECT request_hash, status_code, content_type, response_body
+			 FROM idempotency_keys WHERE user_id=$1 AND key=$2`,
+			userID, key,
+		).Scan(&hash, &status, &contentType, &body)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case hash != requestHash:
+			return ErrIdempotencyMismatch
+		case !status.Valid:
+			return ErrIdempotencyInProgress
+		}
+
+		resp = &IdempotentResponse{
+			StatusCode:  int(status.Int64),
+			ContentType: contentType.String,
+			Body:        body,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос, зарезервированный ClaimIdempotencyKey.
+func (r *Repository) CompleteIdempotencyKey(ctx context.Context, userID int64, key string, resp IdempotentResponse) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE idempotency_keys
+		 SET status_code=$3, content_type=$4, response_body=$5, completed_at=NOW()
+		 WHERE user_id=$1 AND key=$2`,
+		userID, key, resp.StatusCode, resp.ContentType, resp.Body)
+	return err
+}
+
+// ReleaseIdempotencyKey снимает резерв, если запрос не удался (5xx),
+// чтобы клиент мог повторить его с тем же ключом.
+func (r *Repository) ReleaseIdempotencyKey(ctx context.Context, userID int64, key string) error {
+	_, err := r.db.ExecContext(ctx,
+		`DELETE FROM idempotency_keys
+		 WHERE user_id=$1 AND key=$2 AND status_code IS NULL`,
+		userID, key)
+	return err
+}
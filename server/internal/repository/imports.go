@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"invest/internal/ledger"
+	"invest/internal/models"
+	"sort"
+)
+
+//
+// ========================
+//         ИМПОРТ
+// ========================
+//
+
+// ImportOperation — операция импорта. Если Payout.InvestorID == 0, она
+// относится к новому инвестору ImportBatch.NewInvestors[NewInvestor].
+type ImportOperation struct {
+	Payout      models.Payout
+	NewInvestor int
+}
+
+// ImportBatch — всё, что проводится одним импортом.
+type ImportBatch struct {
+	NewInvestors []string
+	Operations   []ImportOperation
+}
+
+// ImportError — операция Operations[Index] не прошла проверку остатка
+// в транзакции (данные изменились после предпросмотра) или её инвестор
+// удалён (Err == sql.ErrNoRows).
+type ImportError struct {
+	Index int
+	Err   error
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("import operation %d: %v", e.Index, e.Err)
+}
+
+func (e *ImportError) Unwrap() error { return e.Err }
+
+// ImportOperations проводит импорт одной транзакцией: создаёт новых
+// инвесторов и все операции либо ничего. Строки существующих инвесторов
+// блокируются (по возрастанию ID), и их остатки перепроверяются
+// ledger.CheckBackdated — как при предпросмотре. ID созданных инвесторов
+// и операций проставляются в batch.
+func (r *Repository) ImportOperations(ctx context.Context, workspaceID, actorID int64, batch *ImportBatch) ([]models.Investor, error) {
+	var created []models.Investor
+
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		// операции по инвесторам: существующие — по ID, новые — по отрицательному ключу
+		groups := make(map[int64][]int)
+		var ids []int64
+		for i, op := range batch.Operations {
+			k := op.Payout.InvestorID
+			if k == 0 {
+				k = -1 - int64(op.NewInvestor)
+			} else if len(groups[k]) == 0 {
+				ids = append(ids, k)
+			}
+			groups[k] = append(groups[k], i)
+		}
+		sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+
+		for _, id := range ids {
+			inv, err := lockInvestor(ctx, tx, workspaceID, id)
+			if err == nil && inv.Status == models.InvestorDeleted {
+				err = sql.ErrNoRows
+			}
+			if errors.Is(err, sql.ErrNoRows) {
+				return &ImportError{Index: groups[id][0], Err: err}
+			}
+			if err != nil {
+				return err
+			}
+
+			rows, err := tx.QueryContext(ctx, payoutSelect+`
+				 WHERE p.investor_id=$1`, id)
+			if err != nil {
+				return err
+			}
+			history, err := scanPayouts(rows)
+			rows.Close()
+			if err != nil {
+				return err
+			}
+
+			if err := checkImported(inv, history, batch, groups[id]); err != nil {
+				return err
+			}
+		}
+
+		created = make([]models.Investor, len(batch.NewInvestors))
+		for i, name := range batch.NewInvestors {
+			inv := &created[i]
+			err := tx.QueryRowContext(ctx,
+				`INSERT INTO investors (workspace_id, full_name)
+				 VALUES ($1, $2)
+				 RETURNING id, full_name, created_at, version, status`,
+				workspaceID, name,
+			).Scan(&inv.ID, &inv.FullName, &inv.CreatedAt, &inv.Version, &inv.Status)
+			if err != nil {
+				return err
+			}
+
+			if err := checkImported(models.Investor{}, nil, batch, groups[-1-int64(i)]); err != nil {
+				return err
+			}
+		}
+
+		for i := range batch.Operations {
+			op := &batch.Operations[i]
+			if op.Payout.InvestorID == 0 {
+				op.Payout.InvestorID = created[op.NewInvestor].ID
+			}
+			op.Payout.CreatedBy = actorRef(actorID)
+			if err := insertPayout(ctx, tx, &op.Payout); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// checkImported — ledger.CheckBackdated для операций batch с индексами idx;
+// первая непрошедшая возвращается как *ImportError.
+func checkImported(inv models.Investor, history []models.Payout, batch *ImportBatch, idx []int) error {
+	added := make([]models.Payout, len(idx))
+	for j, i := range idx {
+		added[j] = batch.Operations[i].Payout
+		added[j].InvestorID = inv.ID
+	}
+	for j, err := range ledger.CheckBackdated(inv, history, added) {
+		if err != nil {
+			return &ImportError{Index: idx[j], Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"invest/internal/models"
+	"strings"
+	"time"
+)
+
+//
+// ========================
+//      INVITES
+// ========================
+//
+
+var (
+	// ErrInviteInvalid — приглашение не найдено, уже использовано или истекло.
+	ErrInviteInvalid = errors.New("invalid or expired invite")
+
+	// ErrInviteEmail — приглашение выписано на другой email.
+	ErrInviteEmail = errors.New("invite was issued for another email")
+)
+
+// CreateInvite сохраняет приглашение. В базе хранится только хеш токена.
+func (r *Repository) CreateInvite(ctx context.Context, inv *models.Invite, tokenHash string) error {
+	return r.q(ctx).QueryRowContext(ctx,
+		`INSERT INTO invites (workspace_id, email, role, token_hash, created_by, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, created_at`,
+		inv.WorkspaceID, inv.Email, inv.Role, tokenHash, inv.CreatedBy, inv.ExpiresAt,
+	).Scan(&inv.ID, &inv.CreatedAt)
+}
+
+func (r *Repository) ListInvites(ctx context.Context, workspaceID int64) ([]models.Invite, error) {
+	rows, err := r.q(ctx).QueryContext(ctx,
+		`SELECT id, workspace_id, email, role, created_by, expires_at, used_at, created_at
+		 FROM invites
+		 WHERE workspace_id=$1
+		 ORDER BY id DESC`,
+		workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.Invite
+	for rows.Next() {
+		var inv models.Invite
+		if err := rows.Scan(
+			&inv.ID, &inv.WorkspaceID, &inv.Email, &inv.Role,
+			&inv.CreatedBy, &inv.ExpiresAt, &inv.UsedAt, &inv.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, inv)
+	}
+	return out, rows.Err()
+}
+
+// RegisterWithInvite погашает приглашение и создаёт пользователя в его пространстве
+// с его ролью — одной транзакцией, поэтому токен нельзя использовать дважды.
+func (r *Repository) RegisterWithInvite(ctx context.Context, tokenHash string, u *models.User) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		var inv models.Invite
+		err := tx.QueryRowContext(ctx,
+			`SELECT id, workspace_id, email, role, expires_at, used_at
+			 FROM invites
+			 WHERE token_hash=$1
+			 FOR UPDATE`,
+			tokenHash,
+		).Scan(&inv.ID, &inv.WorkspaceID, &inv.Email, &inv.Role, &inv.ExpiresAt, &inv.UsedAt)
+		if err == sql.ErrNoRows {
+			return ErrInviteInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		if inv.UsedAt != nil || time.Now().After(inv.ExpiresAt) {
+			return ErrInviteInvalid
+		}
+		if !strings.EqualFold(inv.Email, u.Email) {
+			return ErrInviteEmail
+		}
+
+		u.WorkspaceID = inv.WorkspaceID
+		u.Role = inv.Role
+
+		err = tx.QueryRowContext(ctx,
+			`INSERT INTO users (email, password_hash, workspace_id, role)
+			 VALUES ($1, $2, $3, $4)
+			 RETURNING id, created_at`,
+			u.Email, u.PasswordHash, u.WorkspaceID, u.Role,
+		).Scan(&u.ID, &u.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE invites SET used_at=NOW(), used_by=$1 WHERE id=$2`,
+			u.ID, inv.ID)
+		return err
+	})
+}
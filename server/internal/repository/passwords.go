@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+//
+// ========================
+//      PASSWORDS
+// ========================
+//
+
+// ErrResetInvalid — токен сброса не найден, уже использован или истёк.
+var ErrResetInvalid = errors.New("invalid or expired reset token")
+
+// ChangePassword меняет пароль и закрывает все сессии пользователя, кроме текущей.
+func (r *Repository) ChangePassword(ctx context.Context, userID, keepSessionID int64, passwordHash string) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE users SET password_hash=$1 WHERE id=$2`,
+			passwordHash, userID)
+		if err != nil {
+			return err
+		}
+		if err := expectOneRow(res); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE sessions SET revoked_at=NOW()
+			 WHERE user_id=$1 AND id<>$2 AND revoked_at IS NULL`,
+			userID, keepSessionID)
+		return err
+	})
+}
+
+// CreatePasswordReset сохраняет токен сброса. Прежние неиспользованные
+// токены пользователя перестают действовать.
+func (r *Repository) CreatePasswordReset(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			`UPDATE password_resets SET used_at=NOW()
+			 WHERE user_id=$1 AND used_at IS NULL`,
+			userID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO password_resets (user_id, token_hash, expires_at)
+			 VALUES ($1, $2, $3)`,
+			userID, tokenHash, expiresAt)
+		return err
+	})
+}
+
+// ResetPassword погашает токен, ставит новый пароль, снимает блокировку входа
+// и отзывает все сессии и выданные токены пользователя.
+func (r *Repository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (int64, error) {
+	var userID int64
+
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var (
+			resetID   int64
+			expiresAt time.Time
+			usedAt    *time.Time
+		)
+		err := tx.QueryRowContext(ctx,
+			`SELECT id, user_id, expires_at, used_at
+			 FROM password_resets
+			 WHERE token_hash=$1
+			 FOR UPDATE`,
+			tokenHash,
+		).Scan(&resetID, &userID, &expiresAt, &usedAt)
+		if err == sql.ErrNoRows {
+			return ErrResetInvalid
+		}
+		if err != nil {
+			return err
+		}
+		if usedAt != nil || time.Now().After(expiresAt) {
+			return ErrResetInvalid
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE password_resets SET used_at=NOW() WHERE id=$1`, resetID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE users SET password_hash=$1, failed_logins=0, locked_until=NULL
+			 WHERE id=$2`,
+			passwordHash, userID)
+		if err != nil {
+			return err
+		}
+
+		return revokeAllSessionsTx(ctx, tx, userID)
+	})
+	return userID, err
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"invest/internal/models"
+	"strings"
+)
+
+//
+// ========================
+//    ПОИСК ОПЕРАЦИЙ
+// ========================
+//
+
+// ListPayouts — операции пространства по фильтру, одна страница в порядке f.Sort.
+// more == true, если после страницы есть ещё операции.
+//
+// Пагинация keyset: следующая страница начинается строго после f.After,
+// поэтому новые операции не сдвигают уже выданные страницы.
+// Операции без даты при сортировке по дате идут первыми (по убыванию — последними).
+func (r *Repository) ListPayouts(ctx context.Context, workspaceID int64, f models.PayoutFilter) (list []models.Payout, more bool, err error) {
+	where := []string{"pi.workspace_id=$1"}
+	args := []any{workspaceID}
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.InvestorID != 0 {
+		where = append(where, "p.investor_id="+arg(f.InvestorID))
+	}
+	if f.From != nil {
+		where = append(where, "p.period_date >= "+arg(*f.From))
+	}
+	if f.To != nil {
+		where = append(where, "p.period_date <= "+arg(*f.To))
+	}
+	if len(f.Kinds) > 0 {
+		in := make([]string, 0, len(f.Kinds))
+		for _, k := range f.Kinds {
+			in = append(in, arg(k))
+		}
+		where = append(where, "p.kind IN ("+strings.Join(in, ", ")+")")
+	}
+	if f.MinAmount != nil {
+		where = append(where, "p.payout_amount >= "+arg(*f.MinAmount))
+	}
+	if f.MaxAmount != nil {
+		where = append(where, "p.payout_amount <= "+arg(*f.MaxAmount))
+	}
+
+	dir, cmp := "ASC", ">"
+	if f.Sort.Desc() {
+		dir, cmp = "DESC", "<"
+	}
+
+	var order string
+	if f.Sort.ByAmount() {
+		order = fmt.Sprintf("p.payout_amount %s, p.id %s", dir, dir)
+		if c := f.After; c != nil {
+			where = append(where, fmt.Sprintf("(p.payout_amount, p.id) %s (%s::numeric, %s)",
+				cmp, arg(c.Amount), arg(c.ID)))
+		}
+	} else {
+		// NULL-даты — в начале по возрастанию и в конце по убыванию
+		nulls := "NULLS FIRST"
+		if f.Sort.Desc() {
+			nulls = "NULLS LAST"
+		}
+		order = fmt.Sprintf("p.period_date %s %s, p.id %s", dir, nulls, dir)
+
+		if c := f.After; c != nil {
+			where = append(where, payoutDateAfter(c, f.Sort.Desc(), arg))
+		}
+	}
+
+	query := payoutSelect + `
+         WHERE ` + strings.Join(where, " AND ") + `
+         ORDER BY ` + order + `
+         LIMIT ` + arg(f.Limit+1)
+
+	rows, err := r.q(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	list, err = scanPayouts(rows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(list) > f.Limit {
+		return list[:f.Limit], true, nil
+	}
+	return list, false, nil
+}
+
+// payoutDateAfter — условие «строго после курсора» для сортировки по дате
+// с учётом операций без даты.
+func payoutDateAfter(c *models.PayoutCursor, desc bool, arg func(any) string) string {
+	switch {
+	case c.Date == nil && !desc:
+		// курсор среди NULL-дат: остаток NULL-дат и все датированные
+		return fmt.Sprintf("((p.period_date IS NULL AND p.id > %s) OR p.period_date IS NOT NULL)", arg(c.ID))
+	case c.Date == nil && desc:
+		// NULL-даты последние: дальше только они
+		return fmt.Sprintf("(p.period_date IS NULL AND p.id < %s)", arg(c.ID))
+	case !desc:
+		return fmt.Sprintf("(p.period_date, p.id) > (%s::date, %s)", arg(*c.Date), arg(c.ID))
+	default:
+		return fmt.Sprintf("((p.period_date, p.id) < (%s::date, %s) OR p.period_date IS NULL)", arg(*c.Date), arg(c.ID))
+	}
+}
@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"invest/internal/models"
+	"time"
+)
+
+//
+// ========================
+//      PAYOUT RUNS
+// ========================
+//
+
+// ErrRunStatus — пакет в состоянии, из которого операция невозможна
+// (например, повторный commit или откат черновика).
+var ErrRunStatus = errors.New("payout run is not in the required status")
+
+// ListRatesAt — действующие на дату ставки всех инвесторов.
+func (r *Repository) ListRatesAt(ctx context.Context, workspaceID int64, at time.Time) (map[int64]models.Percent, error) {
+	rows, err := r.q(ctx).QueryContext(ctx,
+		`SELECT DISTINCT ON (rt.investor_id) rt.investor_id, rt.monthly_percent
+		 FROM investor_rates rt
+		 JOIN investors i ON i.id = rt.investor_id
+		 WHERE rt.effective_from <= $1 AND i.workspace_id=$2
+		 ORDER BY rt.investor_id, rt.effective_from DESC`,
+		at, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[int64]models.Percent)
+	for rows.Next() {
+		var id int64
+		var pct models.Percent
+		if err := rows.Scan(&id, &pct); err != nil {
+			return nil, err
+		}
+		out[id] = pct
+	}
+	return out, rows.Err()
+}
+
+// CreatePayoutRun сохраняет пакет и его строки. Если run.Status == committed,
+// в той же транзакции создаются и сами операции.
+func (r *Repository) CreatePayoutRun(ctx context.Context, run *models.PayoutRun) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO payout_runs (workspace_id, period_date, status, period_from, total_profit, created_by)
+			 VALUES ($1, $2, $3, $4, $5, $6)
+			 RETURNING id, created_at`,
+			run.WorkspaceID, run.PeriodDate, models.RunDraft, run.PeriodFrom, run.TotalProfit, run.CreatedBy,
+		).Scan(&run.ID, &run.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		for i := range run.Items {
+			it := &run.Items[i]
+			it.RunID = run.ID
+
+			err := tx.QueryRowContext(ctx,
+				`INSERT INTO payout_run_items (run_id, investor_id, kind, amount, capital, monthly_percent)
+				 VALUES ($1, $2, $3, $4, $5, $6)
+				 RETURNING id`,
+				it.RunID, it.InvestorID, it.Kind, it.Amount, it.Capital, it.MonthlyPercent,
+			).Scan(&it.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		if run.Status != models.RunCommitted {
+			run.Status = models.RunDraft
+			return nil
+		}
+
+		var actorID int64
+		if run.CreatedBy != nil {
+			actorID = *run.CreatedBy
+		}
+		return commitRunTx(ctx, tx, run, actorID)
+	})
+}
+
+// GetPayoutRun — пакет со строками. sql.ErrNoRows, если не найден в пространстве.
+func (r *Repository) GetPayoutRun(ctx context.Context, workspaceID, id int64) (*models.PayoutRun, error) {
+	return getPayoutRun(ctx, r.db, workspaceID, id, false)
+}
+
+// CommitPayoutRun создаёт операции по черновику одной транзакцией.
+// actorID — пользователь, проводящий пакет (created_by операций).
+func (r *Repository) CommitPayoutRun(ctx context.Context, workspaceID, id, actorID int64) (*models.PayoutRun, error) {
+	var run *models.PayoutRun
+
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		run, err = getPayoutRun(ctx, tx, workspaceID, id, true)
+		if err != nil {
+			return err
+		}
+		if run.Status != models.RunDraft {
+			return ErrRunStatus
+		}
+		return commitRunTx(ctx, tx, run, actorID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// RollbackPayoutRun сторнирует операции проведённого пакета.
+func (r *Repository) RollbackPayoutRun(ctx context.Context, workspaceID, id, actorID int64) (*models.PayoutRun, error) {
+	var run *models.PayoutRun
+
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		run, err = getPayoutRun(ctx, tx, workspaceID, id, true)
+		if err != nil {
+			return err
+		}
+		if run.Status != models.RunCommitted {
+			return ErrRunStatus
+		}
+
+		// операции пакета не удаляются, а сторнируются — история сохраняется
+		for _, it := range run.Items {
+			if it.PayoutID == nil {
+				continue
+			}
+
+			orig, err := getPayoutForUpdate(ctx, tx, workspaceID, *it.PayoutID)
+			if err != nil {
+				return err
+			}
+			if orig.Voided() {
+				// уже отменена вручную
+				continue
+			}
+			if _, err := reversePayoutTx(ctx, tx, workspaceID, orig, actorID); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE payout_runs SET status=$1, rolled_back_at=$2 WHERE id=$3`,
+			models.RunRolledBack, now, id); err != nil {
+			return err
+		}
+
+		run.Status = models.RunRolledBack
+		run.RolledBackAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func commitRunTx(ctx context.Context, tx *sql.Tx, run *models.PayoutRun, actorID int64) error {
+	period := run.PeriodDate
+
+	for i := range run.Items {
+		it := &run.Items[i]
+
+		p := models.Payout{
+			InvestorID:   it.InvestorID,
+			PeriodDate:   &period,
+			PayoutAmount: it.Amount,
+			Kind:         it.Kind,
+			RunID:        &run.ID,
+			CreatedBy:    actorRef(actorID),
+		}
+		if err := insertCheckedPayout(ctx, tx, run.WorkspaceID, &p); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE payout_run_items SET payout_id=$1 WHERE id=$2`, p.ID, it.ID); err != nil {
+			return err
+		}
+		it.PayoutID = &p.ID
+	}
+
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE payout_runs SET status=$1, committed_at=$2 WHERE id=$3`,
+		models.RunCommitted, now, run.ID); err != nil {
+		return err
+	}
+
+	run.Status = models.RunCommitted
+	run.CommittedAt = &now
+	return nil
+}
+
+func getPayoutRun(ctx context.Context, q querier, workspaceID, id int64, forUpdate bool) (*models.PayoutRun, error) {
+	query := `SELECT id, workspace_id, period_date, status, created_at, committed_at, rolled_back_at,
+	                 period_from, total_profit, created_by
+	          FROM payout_runs WHERE id=$1 AND workspace_id=$2`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	var run models.PayoutRun
+	err := q.QueryRowContext(ctx, query, id, workspaceID).Scan(
+		&run.ID, &run.WorkspaceID, &run.PeriodDate, &run.Status, &run.CreatedAt, &run.CommittedAt, &run.RolledBackAt,
+		&run.PeriodFrom, &run.TotalProfit, &run.CreatedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx,
+		`SELECT id, run_id, investor_id, kind, amount, capital, monthly_percent, payout_id
+		 FROM payout_run_items
+		 WHERE run_id=$1
+		 ORDER BY investor_id`,
+		id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var it models.PayoutRunItem
+		if err := rows.Scan(
+			&it.ID, &it.RunID, &it.InvestorID, &it.Kind, &it.Amount,
+			&it.Capital, &it.MonthlyPercent, &it.PayoutID,
+		); err != nil {
+			return nil, err
+		}
+		run.Items = append(run.Items, it)
+		run.Total = run.Total.Add(it.Amount)
+	}
+	return &run, rows.Err()
+}
@@ -3,13 +3,74 @@ package repository
 import (
     "context"
     "database/sql"
+    "errors"
+    "invest/internal/ledger"
     "invest/internal/models"
+    "time"
 )
 
 type Repository struct {
     db *sql.DB
 }
 
+var (
+    // ErrNotDeleted — физическое удаление доступно только для удалённых инвесторов.
+    ErrNotDeleted = errors.New("investor must be deleted before purge")
+
+    // ErrVersionConflict — инвестора уже изменили: версия не совпала с ожидаемой.
+    ErrVersionConflict = errors.New("investor was modified by someone else")
+
+    // ErrNonZeroBalance — у инвестора остался капитал или прибыль.
+    ErrNonZeroBalance = errors.New("investor balance is not zero")
+)
+
+// querier — общее у *sql.DB и *sql.Tx, чтобы одни и те же запросы
+// можно было выполнять как отдельно, так и внутри транзакции.
+type querier interface {
+    ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+    QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+    QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// txKey — ключ контекста, в котором InTx передаёт открытую транзакцию.
+type txKey struct{}
+
+// InTx выполняет fn в одной транзакции: все вызовы репозитория с ctx,
+// переданным в fn, идут через неё. Так изменение и запись о нём в журнале
+// (AppendAudit) фиксируются или откатываются вместе.
+func (r *Repository) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+    return r.inTx(ctx, func(tx *sql.Tx) error {
+        return fn(context.WithValue(ctx, txKey{}, tx))
+    })
+}
+
+// q — транзакция из InTx, если ctx её несёт, иначе пул соединений.
+func (r *Repository) q(ctx context.Context) querier {
+    if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+        return tx
+    }
+    return r.db
+}
+
+// inTx выполняет fn в транзакции: commit при успехе, rollback при ошибке.
+// Внутри InTx fn выполняется в уже открытой транзакции.
+func (r *Repository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+    if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+        return fn(tx)
+    }
+
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+
+    if err := fn(tx); err != nil {
+        _ = tx.Rollback()
+        return err
+    }
+    return tx.Commit()
+}
+
 func New(db *sql.DB) *Repository {
     return &Repository{db: db}
 }
@@ -20,10 +81,49 @@ func New(db *sql.DB) *Repository {
 // ========================
 //
 
-func (r *Repository) ListInvestors(ctx context.Context) ([]models.Investor, error) {
-    rows, err := r.db.QueryContext(ctx,
-        `SELECT id, full_name, invested_amount, created_at
-         FROM investors ORDER BY id`)
+// investorSelect — общие колонки investors вместе с текущей ставкой.
+// invested_amount считается по операциям deposit / adjustment; сторно
+// хранится с обратным знаком, поэтому отменённые пары взаимно гасятся.
+const investorSelect = `SELECT i.id, i.full_name,
+                COALESCE((
+                    SELECT SUM(d.payout_amount) FROM payouts d
+                    WHERE d.investor_id = i.id AND d.kind IN ('deposit', 'adjustment')
+                ), 0),
+                i.created_at, i.version,
+                rt.monthly_percent, i.status, i.archived_at, i.deleted_at
+         FROM investors i
+         LEFT JOIN LATERAL (
+             SELECT monthly_percent FROM investor_rates
+             WHERE investor_id = i.id AND effective_from <= CURRENT_DATE
+             ORDER BY effective_from DESC
+             LIMIT 1
+         ) rt ON TRUE`
+
+type rowScanner interface {
+    Scan(dest ...any) error
+}
+
+func scanInvestor(row rowScanner, inv *models.Investor) error {
+    return row.Scan(
+        &inv.ID,
+        &inv.FullName,
+        &inv.InvestedAmount,
+        &inv.CreatedAt,
+        &inv.Version,
+        &inv.MonthlyPercent,
+        &inv.Status,
+        &inv.ArchivedAt,
+        &inv.DeletedAt,
+    )
+}
+
+// ListInvestors — инвесторы в заданном статусе (active / archived / deleted).
+func (r *Repository) ListInvestors(ctx context.Context, workspaceID int64, status models.InvestorStatus) ([]models.Investor, error) {
+    rows, err := r.q(ctx).QueryContext(ctx,
+        investorSelect+`
+         WHERE i.workspace_id=$1 AND i.status=$2
+         ORDER BY i.id`,
+        workspaceID, status)
     if err != nil {
         return nil, err
     }
@@ -32,57 +132,197 @@ func (r *Repository) ListInvestors(ctx context.Context) ([]models.Investor, erro
     var out []models.Investor
     for rows.Next() {
         var inv models.Investor
-        if err := rows.Scan(&inv.ID, &inv.FullName, &inv.InvestedAmount, &inv.CreatedAt); err != nil {
+        if err := scanInvestor(rows, &inv); err != nil {
             return nil, err
         }
         out = append(out, inv)
     }
-    return out, nil
+    return out, rows.Err()
 }
 
-func (r *Repository) CreateInvestor(ctx context.Context, inv *models.Investor) error {
-    return r.db.QueryRowContext(ctx,
-        `INSERT INTO investors (full_name, invested_amount)
-         VALUES ($1, $2)
-         RETURNING id, full_name, invested_amount, created_at`,
-        inv.FullName, inv.InvestedAmount,
-    ).Scan(&inv.ID, &inv.FullName, &inv.InvestedAmount, &inv.CreatedAt)
-}
+// CreateInvestor создаёт инвестора; ненулевой inv.InvestedAmount
+// проводится начальным вложением (deposit) от даты investedAt.
+// Возвращает созданную операцию или nil, если вложения нет.
+func (r *Repository) CreateInvestor(ctx context.Context, workspaceID int64, inv *models.Investor, investedAt time.Time, actorID int64) (*models.Payout, error) {
+    var deposit *models.Payout
 
-func (r *Repository) UpdateInvestor(ctx context.Context, id int64, fullName *string, investedAmount *float64) error {
-    if fullName != nil {
-        _, err := r.db.ExecContext(ctx,
-            `UPDATE investors SET full_name=$1 WHERE id=$2`,
-            *fullName, id)
+    err := r.inTx(ctx, func(tx *sql.Tx) error {
+        err := tx.QueryRowContext(ctx,
+            `INSERT INTO investors (workspace_id, full_name)
+             VALUES ($1, $2)
+             RETURNING id, full_name, created_at, version, status`,
+            workspaceID, inv.FullName,
+        ).Scan(&inv.ID, &inv.FullName, &inv.CreatedAt, &inv.Version, &inv.Status)
         if err != nil {
             return err
         }
+
+        if inv.InvestedAmount.IsZero() {
+            return nil
+        }
+
+        deposit = &models.Payout{
+            InvestorID:   inv.ID,
+            PeriodDate:   &investedAt,
+            PayoutAmount: inv.InvestedAmount,
+            Kind:         models.KindDeposit,
+            CreatedBy:    actorRef(actorID),
+        }
+        return insertPayout(ctx, tx, deposit)
+    })
+    if err != nil {
+        return nil, err
     }
+    return deposit, nil
+}
+
+// InvestorUpdate — частичное изменение инвестора (nil-поля не меняются).
+type InvestorUpdate struct {
+    FullName       *string
+    InvestedAmount *models.Money
+
+    // дата, с которой действует новая вложенная сумма
+    EffectiveFrom time.Time
+
+    // ожидаемая версия строки; 0 — без проверки
+    Version int64
+}
+
+// UpdateInvestor меняет имя и/или вложенную сумму одним UPDATE с проверкой
+// версии (ErrVersionConflict, если строку уже изменили). Вложенная сумма не
+// перезаписывается: разница с текущей проводится корректировкой
+// (adjustment) от даты upd.EffectiveFrom, так что прошлые балансы не сдвигаются.
+// Возвращает созданную корректировку или nil, если сумма не изменилась.
+func (r *Repository) UpdateInvestor(ctx context.Context, workspaceID, id, actorID int64, upd InvestorUpdate) (*models.Payout, error) {
+    var adj *models.Payout
+
+    err := r.inTx(ctx, func(tx *sql.Tx) error {
+        var version int64
+        err := tx.QueryRowContext(ctx,
+            `UPDATE investors
+             SET full_name = COALESCE($1, full_name),
+                 version   = version + 1
+             WHERE id=$2 AND workspace_id=$3 AND ($4 = 0 OR version = $4)
+             RETURNING version`,
+            upd.FullName, id, workspaceID, upd.Version,
+        ).Scan(&version)
+        if errors.Is(err, sql.ErrNoRows) {
+            return investorMissingOrConflict(ctx, tx, workspaceID, id)
+        }
+        if err != nil {
+            return err
+        }
 
-    if investedAmount != nil {
-        _, err := r.db.ExecContext(ctx,
-            `UPDATE investors SET invested_amount=$1 WHERE id=$2`,
-            *investedAmount, id)
+        if upd.InvestedAmount == nil {
+            return nil
+        }
+
+        inv, err := lockInvestor(ctx, tx, workspaceID, id)
         if err != nil {
             return err
         }
+        if inv.Status == models.InvestorDeleted {
+            return sql.ErrNoRows
+        }
+        history, err := investorPayoutsTx(ctx, tx, id)
+        if err != nil {
+            return err
+        }
+        b := ledger.Compute(inv, history)
+
+        delta := upd.InvestedAmount.Sub(b.InvestedAmount)
+        if delta.IsZero() {
+            return nil
+        }
+
+        // первое вложение инвестора, созданного с нулём, — это deposit
+        kind := models.KindAdjustment
+        if b.InvestedAmount.IsZero() {
+            has, err := hasInvestedEntries(ctx, tx, id)
+            if err != nil {
+                return err
+            }
+            if !has {
+                kind = models.KindDeposit
+            }
+        }
+
+        adj = &models.Payout{
+            InvestorID:   id,
+            PeriodDate:   &upd.EffectiveFrom,
+            PayoutAmount: delta,
+            Kind:         kind,
+            CreatedBy:    actorRef(actorID),
+        }
+        // корректировка задним числом не должна увести в минус более поздние снятия
+        if err := ledger.CheckBackdated(inv, history, []models.Payout{*adj})[0]; err != nil {
+            return err
+        }
+        return insertPayout(ctx, tx, adj)
+    })
+    if err != nil {
+        return nil, err
     }
+    return adj, nil
+}
 
-    return nil
+// SetInvestorStatus переводит инвестора в архив, в удалённые или возвращает в активные.
+// История операций при этом не трогается.
+func (r *Repository) SetInvestorStatus(ctx context.Context, workspaceID, id int64, status models.InvestorStatus) error {
+    res, err := r.q(ctx).ExecContext(ctx,
+        `UPDATE investors SET
+             status=$1,
+             version = version + 1,
+             archived_at = CASE WHEN $1 = 'archived' THEN NOW() END,
+             deleted_at  = CASE WHEN $1 = 'deleted'  THEN NOW() END
+         WHERE id=$2 AND workspace_id=$3`,
+        status, id, workspaceID)
+    if err != nil {
+        return err
+    }
+    return expectOneRow(res)
 }
 
-func (r *Repository) DeleteInvestor(ctx context.Context, id int64) error {
-    _, err := r.db.ExecContext(ctx, `DELETE FROM investors WHERE id=$1`, id)
-    return err
+// PurgeInvestor физически удаляет удалённого (status=deleted) инвестора вместе с историей.
+// Отказывает, если баланс инвестора не нулевой.
+func (r *Repository) PurgeInvestor(ctx context.Context, workspaceID, id int64) error {
+    return r.inTx(ctx, func(tx *sql.Tx) error {
+        inv, err := lockInvestor(ctx, tx, workspaceID, id)
+        if err != nil {
+            return err
+        }
+        if inv.Status != models.InvestorDeleted {
+            return ErrNotDeleted
+        }
+
+        b, err := investorBalanceTx(ctx, tx, inv)
+        if err != nil {
+            return err
+        }
+        if !b.CapitalNow.IsZero() || !b.NetProfit.IsZero() {
+            return ErrNonZeroBalance
+        }
+
+        if _, err := tx.ExecContext(ctx,
+            `DELETE FROM payout_run_items WHERE investor_id=$1`, id); err != nil {
+            return err
+        }
+        if _, err := tx.ExecContext(ctx,
+            `DELETE FROM payouts WHERE investor_id=$1`, id); err != nil {
+            return err
+        }
+        _, err = tx.ExecContext(ctx, `DELETE FROM investors WHERE id=$1`, id)
+        return err
+    })
 }
 
-func (r *Repository) GetInvestorByID(ctx context.Context, id int64) (*models.Investor, error) {
+func (r *Repository) GetInvestorByID(ctx context.Context, workspaceID, id int64) (*models.Investor, error) {
     var inv models.Investor
-    err := r.db.QueryRowContext(ctx,
-        `SELECT id, full_name, invested_amount, created_at
-         FROM investors WHERE id=$1`,
-        id,
-    ).Scan(&inv.ID, &inv.FullName, &inv.InvestedAmount, &inv.CreatedAt)
+    err := scanInvestor(r.q(ctx).QueryRowContext(ctx,
+        investorSelect+`
+         WHERE i.id=$1 AND i.workspace_id=$2`,
+        id, workspaceID,
+    ), &inv)
 
     if err != nil {
         return nil, err
@@ -90,24 +330,128 @@ func (r *Repository) GetInvestorByID(ctx context.Context, id int64) (*models.Inv
     return &inv, nil
 }
 
+// expectOneRow — sql.ErrNoRows, если UPDATE/DELETE не задел ни одной строки.
+func expectOneRow(res sql.Result) error {
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return sql.ErrNoRows
+    }
+    return nil
+}
+
+//
+// ========================
+//      RATES
+// ========================
+//
+
+// SetInvestorRate сохраняет ставку с датой начала действия.
+// Повторная запись на ту же дату заменяет ставку этого дня.
+// sql.ErrNoRows, если инвестор не из этого пространства.
+func (r *Repository) SetInvestorRate(ctx context.Context, workspaceID int64, rt *models.InvestorRate) error {
+    return r.q(ctx).QueryRowContext(ctx,
+        `INSERT INTO investor_rates (investor_id, monthly_percent, effective_from)
+         SELECT id, $2, $3 FROM investors WHERE id=$1 AND workspace_id=$4
+         ON CONFLICT (investor_id, effective_from)
+         DO UPDATE SET monthly_percent = EXCLUDED.monthly_percent
+         RETURNING id, created_at`,
+        rt.InvestorID, rt.MonthlyPercent, rt.EffectiveFrom, workspaceID,
+    ).Scan(&rt.ID, &rt.CreatedAt)
+}
+
+func (r *Repository) ListInvestorRates(ctx context.Context, workspaceID, investorID int64) ([]models.InvestorRate, error) {
+    rows, err := r.q(ctx).QueryContext(ctx,
+        `SELECT rt.id, rt.investor_id, rt.monthly_percent, rt.effective_from, rt.created_at
+         FROM investor_rates rt
+         JOIN investors i ON i.id = rt.investor_id
+         WHERE rt.investor_id=$1 AND i.workspace_id=$2
+         ORDER BY rt.effective_from`,
+        investorID, workspaceID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var out []models.InvestorRate
+    for rows.Next() {
+        var rt models.InvestorRate
+        if err := rows.Scan(&rt.ID, &rt.InvestorID, &rt.MonthlyPercent, &rt.EffectiveFrom, &rt.CreatedAt); err != nil {
+            return nil, err
+        }
+        out = append(out, rt)
+    }
+    return out, rows.Err()
+}
+
+// GetInvestorRateAt — ставка, действующая на дату. nil, если ставка не задана.
+func (r *Repository) GetInvestorRateAt(ctx context.Context, workspaceID, investorID int64, at time.Time) (*models.InvestorRate, error) {
+    var rt models.InvestorRate
+
+    err := r.q(ctx).QueryRowContext(ctx,
+        `SELECT rt.id, rt.investor_id, rt.monthly_percent, rt.effective_from, rt.created_at
+         FROM investor_rates rt
+         JOIN investors i ON i.id = rt.investor_id
+         WHERE rt.investor_id=$1 AND rt.effective_from <= $2 AND i.workspace_id=$3
+         ORDER BY rt.effective_from DESC
+         LIMIT 1`,
+        investorID, at, workspaceID,
+    ).Scan(&rt.ID, &rt.InvestorID, &rt.MonthlyPercent, &rt.EffectiveFrom, &rt.CreatedAt)
+
+    if err == sql.ErrNoRows {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &rt, nil
+}
+
 //
 // ========================
 //      PAYOUTS
 // ========================
 //
 
-func (r *Repository) GetPayouts(ctx context.Context) ([]models.Payout, error) {
-    rows, err := r.db.QueryContext(ctx,
-        `SELECT id, investor_id, period_date, payout_amount, reinvest,
-                is_withdrawal_profit, is_withdrawal_capital,
-                is_topup, created_at
-         FROM payouts
-         ORDER BY period_date, id`)
+// payoutSelect — общие колонки payouts; reversed_by_id подтягивается из сторно-записи.
+// Пространство операции определяется через инвестора (pi.workspace_id).
+const payoutSelect = `SELECT p.id, p.investor_id, p.period_date, p.payout_amount, p.kind,
+                p.run_id, p.reverses_id, rv.id, p.replaces_id, p.created_by, p.created_at
+         FROM payouts p
+         JOIN investors pi ON pi.id = p.investor_id
+         LEFT JOIN payouts rv ON rv.reverses_id = p.id`
+
+func (r *Repository) GetPayouts(ctx context.Context, workspaceID int64) ([]models.Payout, error) {
+    rows, err := r.q(ctx).QueryContext(ctx,
+        payoutSelect+`
+         WHERE pi.workspace_id=$1
+         ORDER BY p.period_date, p.id`,
+        workspaceID)
     if err != nil {
         return nil, err
     }
     defer rows.Close()
 
+    return scanPayouts(rows)
+}
+
+func (r *Repository) GetPayoutsByInvestor(ctx context.Context, workspaceID, investorID int64) ([]models.Payout, error) {
+    rows, err := r.q(ctx).QueryContext(ctx,
+        payoutSelect+`
+         WHERE p.investor_id=$1 AND pi.workspace_id=$2
+         ORDER BY p.period_date, p.id`,
+        investorID, workspaceID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    return scanPayouts(rows)
+}
+
+func scanPayouts(rows *sql.Rows) ([]models.Payout, error) {
     var out []models.Payout
     for rows.Next() {
         var p models.Payout
@@ -116,18 +460,21 @@ func (r *Repository) GetPayouts(ctx context.Context) ([]models.Payout, error) {
             &p.InvestorID,
             &p.PeriodDate,
             &p.PayoutAmount,
-            &p.Reinvest,
-            &p.IsWithdrawalProfit,
-            &p.IsWithdrawalCapital,
-            &p.IsTopup,
+            &p.Kind,
+            &p.RunID,
+            &p.ReversesID,
+            &p.ReversedByID,
+            &p.ReplacesID,
+            &p.CreatedBy,
             &p.CreatedAt,
         ); err != nil {
             return nil, err
         }
+        p.Reversed = p.ReversedByID != nil
 
         out = append(out, p)
     }
-    return out, nil
+    return out, rows.Err()
 }
 
 //
@@ -136,20 +483,123 @@ func (r *Repository) GetPayouts(ctx context.Context) ([]models.Payout, error) {
 // ===============================
 //
 
-func (r *Repository) CreatePayout(ctx context.Context, p *models.Payout) error {
-    return r.db.QueryRowContext(ctx,
-        `INSERT INTO payouts (
-            investor_id, period_date, payout_amount,
-            reinvest, is_withdrawal_profit, is_withdrawal_capital, is_topup
-        )
-        VALUES ($1, $2, $3, $4, $5, $6, FALSE)
+// CreatePayout записывает операцию, предварительно заблокировав строку инвестора
+// и проверив доступный остаток — параллельные снятия не могут уйти в минус.
+// Возвращает *ledger.InsufficientFundsError при превышении остатка
+// и models.ErrNonPositiveAmount при нулевой или отрицательной сумме.
+func (r *Repository) CreatePayout(ctx context.Context, workspaceID int64, p *models.Payout) error {
+    return r.inTx(ctx, func(tx *sql.Tx) error {
+        return insertCheckedPayout(ctx, tx, workspaceID, p)
+    })
+}
+
+func insertCheckedPayout(ctx context.Context, tx *sql.Tx, workspaceID int64, p *models.Payout) error {
+    if err := p.CheckAmount(); err != nil {
+        return err
+    }
+
+    b, err := lockInvestorBalance(ctx, tx, workspaceID, p.InvestorID)
+    if err != nil {
+        return err
+    }
+
+    if err := ledger.CheckAvailable(b, *p); err != nil {
+        return err
+    }
+
+    return insertPayout(ctx, tx, p)
+}
+
+// lockInvestorBalance блокирует строку инвестора до конца транзакции
+// и считает его текущий баланс. sql.ErrNoRows, если инвестора нет в пространстве
+// или он удалён.
+func lockInvestorBalance(ctx context.Context, tx *sql.Tx, workspaceID, investorID int64) (ledger.Balance, error) {
+    inv, err := lockInvestor(ctx, tx, workspaceID, investorID)
+    if err != nil {
+        return ledger.Balance{}, err
+    }
+    if inv.Status == models.InvestorDeleted {
+        return ledger.Balance{}, sql.ErrNoRows
+    }
+    return investorBalanceTx(ctx, tx, inv)
+}
+
+// investorMissingOrConflict — почему UPDATE с проверкой версии не задел строку:
+// инвестора нет (sql.ErrNoRows) или версия уже другая (ErrVersionConflict).
+func investorMissingOrConflict(ctx context.Context, tx *sql.Tx, workspaceID, id int64) error {
+    var exists bool
+    err := tx.QueryRowContext(ctx,
+        `SELECT EXISTS (SELECT 1 FROM investors WHERE id=$1 AND workspace_id=$2)`,
+        id, workspaceID,
+    ).Scan(&exists)
+    if err != nil {
+        return err
+    }
+    if !exists {
+        return sql.ErrNoRows
+    }
+    return ErrVersionConflict
+}
+
+// hasInvestedEntries — есть ли у инвестора хоть одна операция deposit / adjustment
+// (в том числе отменённая).
+func hasInvestedEntries(ctx context.Context, tx *sql.Tx, investorID int64) (bool, error) {
+    var has bool
+    err := tx.QueryRowContext(ctx,
+        `SELECT EXISTS (
+             SELECT 1 FROM payouts
+             WHERE investor_id=$1 AND kind IN ('deposit', 'adjustment')
+         )`,
+        investorID,
+    ).Scan(&has)
+    return has, err
+}
+
+func lockInvestor(ctx context.Context, tx *sql.Tx, workspaceID, id int64) (models.Investor, error) {
+    var inv models.Investor
+    err := tx.QueryRowContext(ctx,
+        `SELECT id, full_name, created_at, status
+         FROM investors WHERE id=$1 AND workspace_id=$2
+         FOR UPDATE`,
+        id, workspaceID,
+    ).Scan(&inv.ID, &inv.FullName, &inv.CreatedAt, &inv.Status)
+    return inv, err
+}
+
+func investorBalanceTx(ctx context.Context, tx *sql.Tx, inv models.Investor) (ledger.Balance, error) {
+    payouts, err := investorPayoutsTx(ctx, tx, inv.ID)
+    if err != nil {
+        return ledger.Balance{}, err
+    }
+    return ledger.Compute(inv, payouts), nil
+}
+
+// investorPayoutsTx — вся история операций инвестора внутри транзакции.
+func investorPayoutsTx(ctx context.Context, tx *sql.Tx, investorID int64) ([]models.Payout, error) {
+    rows, err := tx.QueryContext(ctx,
+        payoutSelect+`
+         WHERE p.investor_id=$1`,
+        investorID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+    return scanPayouts(rows)
+}
+
+func insertPayout(ctx context.Context, q querier, p *models.Payout) error {
+    return q.QueryRowContext(ctx,
+        `INSERT INTO payouts (investor_id, period_date, payout_amount, kind, run_id, reverses_id, replaces_id, created_by)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
         RETURNING id, created_at`,
         p.InvestorID,
         p.PeriodDate,
         p.PayoutAmount,
-        p.Reinvest,
-        p.IsWithdrawalProfit,
-        p.IsWithdrawalCapital,
+        p.Kind,
+        p.RunID,
+        p.ReversesID,
+        p.ReplacesID,
+        p.CreatedBy,
     ).Scan(&p.ID, &p.CreatedAt)
 }
 
@@ -159,18 +609,9 @@ func (r *Repository) CreatePayout(ctx context.Context, p *models.Payout) error {
 // ===============================
 //
 
-func (r *Repository) CreateTopup(ctx context.Context, p *models.Payout) error {
-    return r.db.QueryRowContext(ctx,
-        `INSERT INTO payouts (
-            investor_id, period_date, payout_amount,
-            reinvest, is_withdrawal_profit, is_withdrawal_capital, is_topup
-        )
-        VALUES ($1, $2, $3, FALSE, FALSE, FALSE, TRUE)
-        RETURNING id, created_at`,
-        p.InvestorID,
-        p.PeriodDate,
-        p.PayoutAmount,
-    ).Scan(&p.ID, &p.CreatedAt)
+func (r *Repository) CreateTopup(ctx context.Context, workspaceID int64, p *models.Payout) error {
+    p.Kind = models.KindTopup
+    return r.CreatePayout(ctx, workspaceID, p)
 }
 
 //
@@ -179,15 +620,17 @@ func (r *Repository) CreateTopup(ctx context.Context, p *models.Payout) error {
 // ========================
 //
 
-func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+// userSelect — пользователь вместе с политикой 2FA его пространства.
+const userSelect = `SELECT u.id, u.email, u.password_hash, u.workspace_id, u.role,
+                u.totp_enabled, w.require_2fa, u.failed_logins, u.locked_until, u.created_at
+         FROM users u
+         JOIN workspaces w ON w.id = u.workspace_id`
+
+func scanUser(row *sql.Row) (*models.User, error) {
     var u models.User
 
-    err := r.db.QueryRowContext(ctx,
-        `SELECT id, email, password_hash, created_at
-         FROM users
-         WHERE email=$1`,
-        email,
-    ).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.CreatedAt)
+    err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.WorkspaceID, &u.Role,
+        &u.TOTPEnabled, &u.TwoFactorRequired, &u.FailedLogins, &u.LockedUntil, &u.CreatedAt)
 
     if err == sql.ErrNoRows {
         return nil, nil
@@ -199,11 +642,139 @@ func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*models.
     return &u, nil
 }
 
+func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+    return scanUser(r.q(ctx).QueryRowContext(ctx, userSelect+` WHERE u.email=$1`, email))
+}
+
+// GetUserByID — nil, nil если пользователя нет.
+func (r *Repository) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
+    return scanUser(r.q(ctx).QueryRowContext(ctx, userSelect+` WHERE u.id=$1`, id))
+}
+
+// RecordLoginFailure увеличивает счётчик неудачных входов и возвращает его.
+func (r *Repository) RecordLoginFailure(ctx context.Context, userID int64) (int, error) {
+    var n int
+    err := r.q(ctx).QueryRowContext(ctx,
+        `UPDATE users SET failed_logins = failed_logins + 1
+         WHERE id=$1
+         RETURNING failed_logins`,
+        userID,
+    ).Scan(&n)
+    return n, err
+}
+
+// LockUser блокирует вход до указанного момента.
+func (r *Repository) LockUser(ctx context.Context, userID int64, until time.Time) error {
+    _, err := r.q(ctx).ExecContext(ctx,
+        `UPDATE users SET locked_until=$1 WHERE id=$2`,
+        until, userID)
+    return err
+}
+
+// ResetLoginFailures — после успешного входа счётчик и блокировка сбрасываются.
+func (r *Repository) ResetLoginFailures(ctx context.Context, userID int64) error {
+    _, err := r.q(ctx).ExecContext(ctx,
+        `UPDATE users SET failed_logins=0, locked_until=NULL WHERE id=$1`,
+        userID)
+    return err
+}
+
+// actorRef — id пользователя для колонок created_by (0 — не указан).
+func actorRef(actorID int64) *int64 {
+    if actorID == 0 {
+        return nil
+    }
+    return &actorID
+}
+
+// GetDefaultWorkspaceID — основное (самое первое) рабочее пространство.
+func (r *Repository) GetDefaultWorkspaceID(ctx context.Context) (int64, error) {
+    var id int64
+    err := r.q(ctx).QueryRowContext(ctx,
+        `SELECT id FROM workspaces ORDER BY id LIMIT 1`,
+    ).Scan(&id)
+    return id, err
+}
+
+// CreateUserInNewWorkspace создаёт новое рабочее пространство и его первого пользователя.
+func (r *Repository) CreateUserInNewWorkspace(ctx context.Context, u *models.User, workspaceName string) error {
+    return r.inTx(ctx, func(tx *sql.Tx) error {
+        err := tx.QueryRowContext(ctx,
+            `INSERT INTO workspaces (name) VALUES ($1) RETURNING id`,
+            workspaceName,
+        ).Scan(&u.WorkspaceID)
+        if err != nil {
+            return err
+        }
+
+        // создатель пространства — его владелец
+        u.Role = models.RoleOwner
+
+        return tx.QueryRowContext(ctx,
+            `INSERT INTO users (email, password_hash, workspace_id, role)
+             VALUES ($1, $2, $3, $4)
+             RETURNING id, created_at`,
+            u.Email, u.PasswordHash, u.WorkspaceID, u.Role,
+        ).Scan(&u.ID, &u.CreatedAt)
+    })
+}
+
+// CreateUser добавляет пользователя в существующее пространство u.WorkspaceID.
 func (r *Repository) CreateUser(ctx context.Context, u *models.User) error {
-    return r.db.QueryRowContext(ctx,
-        `INSERT INTO users (email, password_hash)
-         VALUES ($1, $2)
+    return r.q(ctx).QueryRowContext(ctx,
+        `INSERT INTO users (email, password_hash, workspace_id, role)
+         VALUES ($1, $2, $3, $4)
          RETURNING id, created_at`,
-        u.Email, u.PasswordHash,
+        u.Email, u.PasswordHash, u.WorkspaceID, u.Role,
     ).Scan(&u.ID, &u.CreatedAt)
 }
+
+// CountAllUsers — пользователи во всех пространствах (0 — система ещё не настроена).
+func (r *Repository) CountAllUsers(ctx context.Context) (int, error) {
+    var n int
+    err := r.q(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&n)
+    return n, err
+}
+
+func (r *Repository) CountUsers(ctx context.Context, workspaceID int64) (int, error) {
+    var n int
+    err := r.q(ctx).QueryRowContext(ctx,
+        `SELECT COUNT(*) FROM users WHERE workspace_id=$1`,
+        workspaceID,
+    ).Scan(&n)
+    return n, err
+}
+
+func (r *Repository) ListUsers(ctx context.Context, workspaceID int64) ([]models.User, error) {
+    rows, err := r.q(ctx).QueryContext(ctx,
+        `SELECT id, email, workspace_id, role, totp_enabled, created_at
+         FROM users
+         WHERE workspace_id=$1
+         ORDER BY id`,
+        workspaceID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var out []models.User
+    for rows.Next() {
+        var u models.User
+        if err := rows.Scan(&u.ID, &u.Email, &u.WorkspaceID, &u.Role, &u.TOTPEnabled, &u.CreatedAt); err != nil {
+            return nil, err
+        }
+        out = append(out, u)
+    }
+    return out, rows.Err()
+}
+
+// SetUserRole меняет роль пользователя своего пространства. sql.ErrNoRows, если такого нет.
+func (r *Repository) SetUserRole(ctx context.Context, workspaceID, id int64, role models.Role) error {
+    res, err := r.q(ctx).ExecContext(ctx,
+        `UPDATE users SET role=$1 WHERE id=$2 AND workspace_id=$3`,
+        role, id, workspaceID)
+    if err != nil {
+        return err
+    }
+    return expectOneRow(res)
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"invest/internal/ledger"
+	"invest/internal/models"
+	"time"
+)
+
+//
+// ========================
+//   СТОРНО И ИСПРАВЛЕНИЯ
+// ========================
+//
+
+var (
+	ErrPayoutReversed = errors.New("payout is already reversed")
+	ErrReversalEntry  = errors.New("reversal entries cannot be reversed")
+)
+
+// ReversePayout создаёт компенсирующую запись к операции id.
+// Исходная запись не меняется; пара взаимно погашается в расчёте баланса.
+// actorID — пользователь, проводящий сторно.
+func (r *Repository) ReversePayout(ctx context.Context, workspaceID, id, actorID int64) (*models.Payout, error) {
+	var rev *models.Payout
+
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		orig, err := getPayoutForUpdate(ctx, tx, workspaceID, id)
+		if err != nil {
+			return err
+		}
+
+		rev, err = reversePayoutTx(ctx, tx, workspaceID, orig, actorID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+// CorrectPayout отменяет операцию id и проводит её заново с новой суммой и/или датой.
+// nil-параметры берутся из исходной операции.
+func (r *Repository) CorrectPayout(ctx context.Context, workspaceID, id, actorID int64, amount *models.Money, date *time.Time) (rev, corrected *models.Payout, err error) {
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		orig, err := getPayoutForUpdate(ctx, tx, workspaceID, id)
+		if err != nil {
+			return err
+		}
+
+		rev, err = reversePayoutTx(ctx, tx, workspaceID, orig, actorID)
+		if err != nil {
+			return err
+		}
+
+		corrected = &models.Payout{
+			InvestorID:   orig.InvestorID,
+			PeriodDate:   orig.PeriodDate,
+			PayoutAmount: orig.PayoutAmount,
+			Kind:         orig.Kind,
+			ReplacesID:   &orig.ID,
+			CreatedBy:    actorRef(actorID),
+		}
+		if amount != nil {
+			corrected.PayoutAmount = *amount
+		}
+		if date != nil {
+			corrected.PeriodDate = date
+		}
+
+		return insertCheckedPayout(ctx, tx, workspaceID, corrected)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return rev, corrected, nil
+}
+
+// reversePayoutTx — сторно внутри уже открытой транзакции.
+// Сторно датируется той же датой, что и исходная операция, поэтому
+// балансы «на дату» не получают промежуточного скачка.
+func reversePayoutTx(ctx context.Context, tx *sql.Tx, workspaceID int64, orig *models.Payout, actorID int64) (*models.Payout, error) {
+	if orig.ReversesID != nil {
+		return nil, ErrReversalEntry
+	}
+	if orig.ReversedByID != nil {
+		return nil, ErrPayoutReversed
+	}
+
+	// ReversedByID из getPayoutForUpdate мог устареть: если мы ждали блокировку,
+	// пока параллельная транзакция проводила сторно, то LEFT JOIN её записи
+	// не видит. После блокировки проверяем заново, иначе вместо 409 получим
+	// нарушение idx_payouts_reverses_id.
+	var reversed bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM payouts WHERE reverses_id=$1)`,
+		orig.ID).Scan(&reversed); err != nil {
+		return nil, err
+	}
+	if reversed {
+		return nil, ErrPayoutReversed
+	}
+
+	b, err := lockInvestorBalance(ctx, tx, workspaceID, orig.InvestorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ledger.CheckReversal(b, *orig); err != nil {
+		return nil, err
+	}
+
+	rev := &models.Payout{
+		InvestorID:   orig.InvestorID,
+		PeriodDate:   orig.PeriodDate,
+		PayoutAmount: orig.PayoutAmount.Neg(),
+		Kind:         orig.Kind,
+		ReversesID:   &orig.ID,
+		CreatedBy:    actorRef(actorID),
+	}
+	if err := insertPayout(ctx, tx, rev); err != nil {
+		return nil, err
+	}
+
+	orig.ReversedByID = &rev.ID
+	orig.Reversed = true
+	return rev, nil
+}
+
+// getPayoutForUpdate — операция по id с блокировкой строки.
+// sql.ErrNoRows, если её нет в пространстве.
+func getPayoutForUpdate(ctx context.Context, tx *sql.Tx, workspaceID, id int64) (*models.Payout, error) {
+	rows, err := tx.QueryContext(ctx,
+		payoutSelect+`
+		 WHERE p.id=$1 AND pi.workspace_id=$2
+		 FOR UPDATE OF p`,
+		id, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	list, err := scanPayouts(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &list[0], nil
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"invest/internal/models"
+	"time"
+)
+
+//
+// ========================
+//      SESSIONS
+// ========================
+//
+
+var (
+	// ErrSessionInvalid — refresh-токен не найден, истёк или сессия отозвана.
+	ErrSessionInvalid = errors.New("invalid or expired session")
+
+	// ErrSessionReused — предъявлен уже обменянный refresh-токен.
+	// Сессия при этом отзывается: токен, скорее всего, утёк.
+	ErrSessionReused = errors.New("refresh token reuse detected")
+)
+
+// CreateSession открывает сессию пользователя и возвращает её id.
+func (r *Repository) CreateSession(ctx context.Context, userID int64, refreshHash string, expiresAt time.Time) (int64, error) {
+	var id int64
+	err := r.q(ctx).QueryRowContext(ctx,
+		`INSERT INTO sessions (user_id, refresh_hash, expires_at, last_used_at)
+		 VALUES ($1, $2, $3, NOW())
+		 RETURNING id`,
+		userID, refreshHash, expiresAt,
+	).Scan(&id)
+	return id, err
+}
+
+// RotateSession обменивает refresh-токен на новый: старый хеш становится
+// previous_hash и больше не принимается. Возвращает пользователя (с актуальными
+// ролью, пространством и состоянием 2FA) и id сессии.
+func (r *Repository) RotateSession(ctx context.Context, refreshHash, newHash string, expiresAt time.Time) (*models.User, int64, error) {
+	var (
+		u         *models.User
+		userID    int64
+		sessionID int64
+	)
+
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var (
+			current   string
+			expires   time.Time
+			revokedAt *time.Time
+		)
+		err := tx.QueryRowContext(ctx,
+			`SELECT id, user_id, refresh_hash, expires_at, revoked_at
+			 FROM sessions
+			 WHERE refresh_hash=$1 OR previous_hash=$1
+			 FOR UPDATE`,
+			refreshHash,
+		).Scan(&sessionID, &userID, &current, &expires, &revokedAt)
+		if err == sql.ErrNoRows {
+			return ErrSessionInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		if revokedAt != nil || time.Now().After(expires) {
+			return ErrSessionInvalid
+		}
+
+		if current != refreshHash {
+			return ErrSessionReused
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE sessions
+			 SET refresh_hash=$1, previous_hash=$2, expires_at=$3, last_used_at=NOW()
+			 WHERE id=$4`,
+			newHash, refreshHash, expiresAt, sessionID)
+		if err != nil {
+			return err
+		}
+
+		u, err = scanUser(tx.QueryRowContext(ctx, userSelect+` WHERE u.id=$1`, userID))
+		if err == nil && u == nil {
+			return ErrSessionInvalid
+		}
+		return err
+	})
+
+	// транзакция при ошибке откатывается, поэтому сессию с повторно
+	// использованным токеном отзываем отдельным запросом
+	if errors.Is(err, ErrSessionReused) {
+		_, rerr := r.db.ExecContext(ctx,
+			`UPDATE sessions SET revoked_at=NOW() WHERE id=$1 AND revoked_at IS NULL`, sessionID)
+		if rerr != nil {
+			return nil, 0, rerr
+		}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return u, sessionID, nil
+}
+
+// RevokeSession закрывает одну сессию пользователя (logout).
+func (r *Repository) RevokeSession(ctx context.Context, userID, sessionID int64) error {
+	_, err := r.q(ctx).ExecContext(ctx,
+		`UPDATE sessions SET revoked_at=NOW()
+		 WHERE id=$1 AND user_id=$2 AND revoked_at IS NULL`,
+		sessionID, userID)
+	return err
+}
+
+// RevokeAllSessions закрывает все сессии пользователя и отзывает уже выданные
+// access-токены (withAuth сверяет iat с tokens_revoked_at).
+func (r *Repository) RevokeAllSessions(ctx context.Context, userID int64) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		return revokeAllSessionsTx(ctx, tx, userID)
+	})
+}
+
+func revokeAllSessionsTx(ctx context.Context, tx *sql.Tx, userID int64) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE sessions SET revoked_at=NOW()
+		 WHERE user_id=$1 AND revoked_at IS NULL`,
+		userID)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE users SET tokens_revoked_at=NOW() WHERE id=$1`, userID)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// SessionActive проверяет access-токен: пользователь существует, токен выдан
+// после tokens_revoked_at, а его сессия (если указана) не отозвана.
+func (r *Repository) SessionActive(ctx context.Context, userID, sessionID int64, issuedAt time.Time) (bool, error) {
+	var (
+		tokensRevokedAt *time.Time
+		sessionOK       bool
+	)
+	err := r.q(ctx).QueryRowContext(ctx,
+		`SELECT u.tokens_revoked_at,
+		        EXISTS (SELECT 1 FROM sessions s
+		                WHERE s.id=$2 AND s.user_id=u.id AND s.revoked_at IS NULL)
+		 FROM users u
+		 WHERE u.id=$1`,
+		userID, sessionID,
+	).Scan(&tokensRevokedAt, &sessionOK)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if tokenRevoked(issuedAt, tokensRevokedAt) {
+		return false, nil
+	}
+	return sessionOK, nil
+}
+
+// tokenRevoked — токен выдан раньше tokens_revoked_at. iat в JWT хранится
+// с точностью до секунды, а NOW() — до микросекунд, поэтому отзыв
+// сравнивается по секундам: иначе токен, выданный в ту же секунду, что
+// и выход со всех устройств, отклонялся бы всегда.
+func tokenRevoked(issuedAt time.Time, revokedAt *time.Time) bool {
+	return revokedAt != nil && issuedAt.Before(revokedAt.Truncate(time.Second))
+}
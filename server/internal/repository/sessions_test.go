@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenRevoked(t *testing.T) {
+	revoked := time.Date(2024, 3, 1, 12, 0, 5, 700_000_000, time.UTC)
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		revoked  *time.Time
+		want     bool
+	}{
+		{name: "never revoked", issuedAt: revoked.Add(-time.Hour), revoked: nil, want: false},
+		{name: "issued a second earlier", issuedAt: time.Date(2024, 3, 1, 12, 0, 4, 0, time.UTC), revoked: &revoked, want: true},
+		{name: "issued long before", issuedAt: revoked.Add(-time.Hour).Truncate(time.Second), revoked: &revoked, want: true},
+
+		// iat из JWT — целые секунды: новый токен в ту же секунду действует
+		{name: "issued in the same second", issuedAt: time.Date(2024, 3, 1, 12, 0, 5, 0, time.UTC), revoked: &revoked, want: false},
+		{name: "issued a second later", issuedAt: time.Date(2024, 3, 1, 12, 0, 6, 0, time.UTC), revoked: &revoked, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := tokenRevoked(tt.issuedAt, tt.revoked); got != tt.want {
+			t.Errorf("%s: tokenRevoked() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
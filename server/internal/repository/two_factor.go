@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"invest/internal/models"
+)
+
+//
+// ========================
+//      TWO-FACTOR (TOTP)
+// ========================
+//
+
+// ErrTwoFactorState — 2FA уже включена (setup) или нет ожидающего секрета (enable).
+var ErrTwoFactorState = errors.New("two-factor authentication is in a different state")
+
+// GetTwoFactor — состояние 2FA пользователя вместе с секретами (для проверки кодов).
+func (r *Repository) GetTwoFactor(ctx context.Context, userID int64) (*models.TwoFactor, error) {
+	var (
+		tf              models.TwoFactor
+		secret, pending sql.NullString
+	)
+	err := r.q(ctx).QueryRowContext(ctx,
+		`SELECT u.totp_enabled, w.require_2fa, u.totp_secret, u.totp_pending_secret,
+		        (SELECT COUNT(*) FROM recovery_codes rc
+		         WHERE rc.user_id = u.id AND rc.used_at IS NULL)
+		 FROM users u
+		 JOIN workspaces w ON w.id = u.workspace_id
+		 WHERE u.id=$1`,
+		userID,
+	).Scan(&tf.Enabled, &tf.Required, &secret, &pending, &tf.RecoveryCodesLeft)
+	if err != nil {
+		return nil, err
+	}
+
+	tf.Secret = secret.String
+	tf.PendingSecret = pending.String
+	return &tf, nil
+}
+
+// SetPendingTOTPSecret запоминает новый секрет до подтверждения первым кодом.
+func (r *Repository) SetPendingTOTPSecret(ctx context.Context, userID int64, secret string) error {
+	res, err := r.q(ctx).ExecContext(ctx,
+		`UPDATE users SET totp_pending_secret=$1
+		 WHERE id=$2 AND NOT totp_enabled`,
+		secret, userID)
+	if err != nil {
+		return err
+	}
+	if err := expectOneRow(res); err != nil {
+		return ErrTwoFactorState
+	}
+	return nil
+}
+
+// EnableTOTP включает 2FA с ожидающим секретом и заменяет коды восстановления.
+// step — шаг кода, которым подтверждено включение (повторно его не примем).
+func (r *Repository) EnableTOTP(ctx context.Context, userID, step int64, recoveryHashes []string) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE users
+			 SET totp_secret=totp_pending_secret, totp_pending_secret=NULL,
+			     totp_enabled=TRUE, totp_last_step=$1
+			 WHERE id=$2 AND NOT totp_enabled AND totp_pending_secret IS NOT NULL`,
+			step, userID)
+		if err != nil {
+			return err
+		}
+		if err := expectOneRow(res); err != nil {
+			return ErrTwoFactorState
+		}
+
+		return replaceRecoveryCodes(ctx, tx, userID, recoveryHashes)
+	})
+}
+
+// DisableTOTP выключает 2FA и удаляет коды восстановления.
+func (r *Repository) DisableTOTP(ctx context.Context, userID int64) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			`UPDATE users
+			 SET totp_secret=NULL, totp_pending_secret=NULL,
+			     totp_enabled=FALSE, totp_last_step=NULL
+			 WHERE id=$1`,
+			userID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id=$1`, userID)
+		return err
+	})
+}
+
+// ReplaceRecoveryCodes выдаёт новый набор кодов, старые перестают действовать.
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		return replaceRecoveryCodes(ctx, tx, userID, hashes)
+	})
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int64, hashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	for _, h := range hashes {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`,
+			userID, h)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseTOTPStep отмечает шаг кода использованным. false — этот или более поздний
+// код уже был принят (повтор).
+func (r *Repository) UseTOTPStep(ctx context.Context, userID, step int64) (bool, error) {
+	res, err := r.q(ctx).ExecContext(ctx,
+		`UPDATE users SET totp_last_step=$1
+		 WHERE id=$2 AND (totp_last_step IS NULL OR totp_last_step < $1)`,
+		step, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// UseRecoveryCode погашает код восстановления. false — кода нет или он уже использован.
+func (r *Repository) UseRecoveryCode(ctx context.Context, userID int64, hash string) (bool, error) {
+	res, err := r.q(ctx).ExecContext(ctx,
+		`UPDATE recovery_codes SET used_at=NOW()
+		 WHERE id = (SELECT id FROM recovery_codes
+		             WHERE user_id=$1 AND code_hash=$2 AND used_at IS NULL
+		             LIMIT 1)`,
+		userID, hash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// SetWorkspaceRequire2FA включает/выключает обязательную 2FA для пространства.
+func (r *Repository) SetWorkspaceRequire2FA(ctx context.Context, workspaceID int64, required bool) error {
+	res, err := r.q(ctx).ExecContext(ctx,
+		`UPDATE workspaces SET require_2fa=$1 WHERE id=$2`,
+		required, workspaceID)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
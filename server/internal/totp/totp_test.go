@@ -0,0 +1,131 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// секрет из приложения B RFC 6238 (SHA1): ASCII "12345678901234567890"
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeAt(t *testing.T) {
+	// RFC 6238, приложение B; у нас 6 цифр — последние 6 из 8
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},          // 94287082
+		{1111111109, "081804"},  // 07081804
+		{1111111111, "050471"},  // 14050471
+		{1234567890, "005924"},  // 89005924
+		{2000000000, "279037"},  // 69279037
+		{20000000000, "353130"}, // 65353130
+	}
+
+	for _, tt := range tests {
+		got, err := CodeAt(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("CodeAt(%d): %v", tt.unix, err)
+		}
+		if got != tt.code {
+			t.Errorf("CodeAt(%d) = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+
+	// секрет принимается в любом регистре и с пробелами по краям
+	if got, _ := CodeAt(" "+strings.ToLower(rfcSecret)+" ", 1); got != "287082" {
+		t.Errorf("CodeAt(lowercase secret) = %s, want 287082", got)
+	}
+	if _, err := CodeAt("not base32!", 1); err == nil {
+		t.Error("CodeAt(invalid secret): want error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+	code := func(d int64) string {
+		c, err := CodeAt(rfcSecret, step+d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", secret: rfcSecret, code: code(0), wantStep: step, wantOK: true},
+		{name: "previous step", secret: rfcSecret, code: code(-1), wantStep: step - 1, wantOK: true},
+		{name: "next step", secret: rfcSecret, code: code(1), wantStep: step + 1, wantOK: true},
+		{name: "spaces are ignored", secret: rfcSecret, code: " " + code(0)[:3] + " " + code(0)[3:], wantStep: step, wantOK: true},
+
+		{name: "two steps ago", secret: rfcSecret, code: code(-2)},
+		{name: "two steps ahead", secret: rfcSecret, code: code(2)},
+		{name: "too short", secret: rfcSecret, code: code(0)[:5]},
+		{name: "too long", secret: rfcSecret, code: code(0) + "0"},
+		{name: "invalid secret", secret: "not base32!", code: code(0)},
+	}
+
+	for _, tt := range tests {
+		got, ok := Validate(tt.secret, tt.code, now)
+		if ok != tt.wantOK || got != tt.wantStep {
+			t.Errorf("%s: Validate() = %d, %v; want %d, %v", tt.name, got, ok, tt.wantStep, tt.wantOK)
+		}
+	}
+}
+
+// TestValidateReplay — защита от повтора: repository.UseTOTPStep принимает
+// код, только если его шаг больше последнего принятого. Для этого Validate
+// должен возвращать шаг самого кода, а не текущий: тот же код через 30 секунд
+// ещё проходит окно ±1 шаг, но с прежним шагом.
+func TestValidateReplay(t *testing.T) {
+	var last int64
+	use := func(step int64) bool {
+		if step <= last {
+			return false
+		}
+		last = step
+		return true
+	}
+
+	now := time.Unix(1234567890, 0)
+	code, err := CodeAt(rfcSecret, Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		code   string
+		at     time.Time
+		wantOK bool
+	}{
+		{name: "first use", code: code, at: now, wantOK: true},
+		{name: "same code, same step", code: code, at: now},
+		{name: "same code, next step", code: code, at: now.Add(Period * time.Second)},
+		{name: "new code, next step", at: now.Add(Period * time.Second), wantOK: true},
+		{name: "older code after newer one", code: code, at: now.Add(Period * time.Second)},
+	}
+
+	for _, tt := range tests {
+		if tt.code == "" {
+			if tt.code, err = CodeAt(rfcSecret, Step(tt.at)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		step, ok := Validate(rfcSecret, tt.code, tt.at)
+		if !ok {
+			t.Fatalf("%s: Validate() rejected a code inside the window", tt.name)
+		}
+		if got := use(step); got != tt.wantOK {
+			t.Errorf("%s: accepted = %v, want %v", tt.name, got, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========================
+//         ЧТЕНИЕ
+// ========================
+
+const (
+	// максимальный размер одной части книги после распаковки
+	maxPartSize = 64 << 20
+
+	// строк на листе в Excel
+	maxRows = 1 << 20
+)
+
+var ErrNotWorkbook = errors.New("file is not an xlsx workbook")
+
+// SheetData — значения ячеек листа: Rows[i][j] — строка i+1, колонка j+1.
+// Формулы читаются по сохранённому значению, даты — серийным номером
+// (см. DateFromSerial), пустые ячейки — пустыми строками.
+type SheetData struct {
+	Name string
+	Rows [][]string
+}
+
+// Read читает все листы книги по порядку.
+func Read(r io.ReaderAt, size int64) ([]SheetData, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, ErrNotWorkbook
+	}
+	files := make(map[string]*zip.File, len(z.File))
+	for _, f := range z.File {
+		files[f.Name] = f
+	}
+
+	var wb struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodePart(files, "xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	}
+
+	var rels struct {
+		Items []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodePart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string, len(rels.Items))
+	for _, rel := range rels.Items {
+		// цель указывается относительно xl/ или от корня архива
+		t := rel.Target
+		if strings.HasPrefix(t, "/") {
+			t = strings.TrimPrefix(t, "/")
+		} else {
+			t = path.Join("xl", t)
+		}
+		targets[rel.ID] = t
+	}
+
+	shared, err := readSharedStrings(files)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]SheetData, 0, len(wb.Sheets))
+	for _, s := range wb.Sheets {
+		name, ok := targets[s.RID]
+		if !ok {
+			return nil, fmt.Errorf("%w: no part for sheet %q", ErrNotWorkbook, s.Name)
+		}
+		rows, err := readSheet(files, name, shared)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, SheetData{Name: s.Name, Rows: rows})
+	}
+	return out, nil
+}
+
+// DateFromSerial — дата по серийному номеру Excel (система 1900).
+func DateFromSerial(serial float64) time.Time {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	return epoch.AddDate(0, 0, int(math.Floor(serial)))
+}
+
+func openPart(files map[string]*zip.File, name string) ([]byte, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrNotWorkbook, name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxPartSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxPartSize {
+		return nil, fmt.Errorf("%s is too large", name)
+	}
+	return data, nil
+}
+
+func decodePart(files map[string]*zip.File, name string, v any) error {
+	data, err := openPart(files, name)
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrNotWorkbook, name, err)
+	}
+	return nil
+}
+
+// richText — <si> или <is>: простой текст или набор фрагментов с форматированием.
+type richText struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (rt richText) String() string {
+	if len(rt.R) == 0 {
+		return rt.T
+	}
+	var b strings.Builder
+	b.WriteString(rt.T)
+	for _, r := range rt.R {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+func readSharedStrings(files map[string]*zip.File) ([]string, error) {
+	if _, ok := files["xl/sharedStrings.xml"]; !ok {
+		return nil, nil
+	}
+	var sst struct {
+		Items []richText `xml:"si"`
+	}
+	if err := decodePart(files, "xl/sharedStrings.xml", &sst); err != nil {
+		return nil, err
+	}
+	out := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		out[i] = si.String()
+	}
+	return out, nil
+}
+
+func readSheet(files map[string]*zip.File, name string, shared []string) ([][]string, error) {
+	data, err := openPart(files, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var ws struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				R  string   `xml:"r,attr"`
+				T  string   `xml:"t,attr"`
+				V  string   `xml:"v"`
+				Is richText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&ws); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrNotWorkbook, name, err)
+	}
+
+	var rows [][]string
+	for _, row := range ws.Rows {
+		// номера строк и ячеек необязательны: без них — по порядку
+		r := row.R
+		if r == 0 {
+			r = len(rows) + 1
+		}
+		if r > maxRows {
+			return nil, fmt.Errorf("%w: %s: row %d is out of range", ErrNotWorkbook, name, r)
+		}
+		if r > len(rows) {
+			rows = append(rows, make([][]string, r-len(rows))...)
+		}
+
+		var cells []string
+		for j, c := range row.Cells {
+			col := j + 1
+			if c.R != "" {
+				if col, err = refColumn(c.R); err != nil {
+					return nil, fmt.Errorf("%w: %s: %v", ErrNotWorkbook, name, err)
+				}
+			}
+
+			var v string
+			switch c.T {
+			case "s":
+				n, err := strconv.Atoi(c.V)
+				if err != nil || n < 0 || n >= len(shared) {
+					return nil, fmt.Errorf("%w: %s!%s: bad shared string", ErrNotWorkbook, name, c.R)
+				}
+				v = shared[n]
+			case "inlineStr":
+				v = c.Is.String()
+			default:
+				// n, str (результат формулы), b, e
+				v = c.V
+			}
+
+			if col > len(cells) {
+				cells = append(cells, make([]string, col-len(cells))...)
+			}
+			cells[col-1] = v
+		}
+		rows[r-1] = cells
+	}
+	return rows, nil
+}
+
+// refColumn — номер колонки из адреса ячейки: "AB12" → 28.
+func refColumn(ref string) (int, error) {
+	col := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A'+1)
+		if col > 16384 {
+			break
+		}
+	}
+	if col == 0 || col > 16384 {
+		return 0, fmt.Errorf("bad cell reference %q", ref)
+	}
+	return col, nil
+}
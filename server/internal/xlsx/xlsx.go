@@ -0,0 +1,367 @@
+// Package xlsx — минимальная запись книг Office Open XML (.xlsx) без
+// внешних зависимостей: текст, числа, даты, формулы и несколько
+// фиксированных стилей. Строки пишутся inline, без sharedStrings.
+// Read читает значения ячеек из книг, сохранённых любой программой.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Style — индекс формата ячейки в styles.xml (см. stylesXML).
+type Style int
+
+const (
+	StyleDefault   Style = iota
+	StyleHeader          // жирный текст с заливкой
+	StyleMoney           // #,##0.00
+	StyleMoneyBold       // #,##0.00, жирный — итоги
+	StyleDate            // dd.mm.yyyy
+	StylePercent         // 0.00 (ставка в процентах, не доля)
+)
+
+type cellKind int
+
+const (
+	kindEmpty cellKind = iota
+	kindText
+	kindNumber
+	kindFormula
+)
+
+// Cell — значение ячейки. Создаётся конструкторами Text, Number, Date, Formula.
+type Cell struct {
+	kind    cellKind
+	text    string
+	number  string // десятичная запись числа
+	formula string
+	style   Style
+}
+
+// Empty — пустая ячейка (пропуск колонки).
+func Empty() Cell { return Cell{} }
+
+// Text — строка.
+func Text(s string) Cell { return Cell{kind: kindText, text: s} }
+
+// Header — строка в стиле заголовка.
+func Header(s string) Cell { return Cell{kind: kindText, text: s, style: StyleHeader} }
+
+// Number — число в десятичной записи ("1234.56"), чтобы не терять копейки на float.
+func Number(decimal string, style Style) Cell {
+	return Cell{kind: kindNumber, number: decimal, style: style}
+}
+
+// Int — целое число.
+func Int(n int64) Cell { return Number(strconv.FormatInt(n, 10), StyleDefault) }
+
+// Date — дата (серийный номер Excel в стиле StyleDate).
+func Date(t time.Time) Cell {
+	return Number(strconv.FormatInt(excelDay(t), 10), StyleDate)
+}
+
+// Formula — формула без ведущего "=". cached — заранее посчитанное значение
+// (десятичная запись), его покажут программы, которые не пересчитывают книгу;
+// пустая строка — без значения.
+func Formula(expr, cached string, style Style) Cell {
+	return Cell{kind: kindFormula, formula: expr, number: cached, style: style}
+}
+
+// excelDay — номер дня в системе 1900 (с учётом несуществующего 29.02.1900).
+func excelDay(t time.Time) int64 {
+	d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	return int64(d.Sub(epoch).Hours() / 24)
+}
+
+// ColName — буквенное имя колонки: 1 → A, 27 → AA.
+func ColName(col int) string {
+	var b []byte
+	for col > 0 {
+		col--
+		b = append([]byte{byte('A' + col%26)}, b...)
+		col /= 26
+	}
+	return string(b)
+}
+
+// Ref — адрес ячейки: Ref(2, 3) → "B3".
+func Ref(col, row int) string { return ColName(col) + strconv.Itoa(row) }
+
+// SheetRef — адрес на другом листе: 'Лист 1'!B3.
+func SheetRef(sheet, ref string) string {
+	return "'" + strings.ReplaceAll(sheet, "'", "''") + "'!" + ref
+}
+
+// ========================
+//        WORKBOOK
+// ========================
+
+type Workbook struct {
+	sheets []*Sheet
+	names  map[string]bool
+}
+
+func New() *Workbook {
+	return &Workbook{names: make(map[string]bool)}
+}
+
+// Sheet — лист книги. Строки добавляются по порядку.
+type Sheet struct {
+	name      string
+	widths    map[int]float64
+	rows      [][]Cell
+	freezeRow int
+}
+
+// AddSheet добавляет лист. Имя приводится к правилам Excel: без символов
+// []:*?/\, не длиннее 31 символа и уникальное в книге.
+func (wb *Workbook) AddSheet(name string) *Sheet {
+	name = sheetName(name)
+	base := name
+	for i := 2; wb.names[strings.ToLower(name)]; i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		name = truncateRunes(base, 31-len(suffix)) + suffix
+	}
+	wb.names[strings.ToLower(name)] = true
+
+	s := &Sheet{name: name, widths: make(map[int]float64)}
+	wb.sheets = append(wb.sheets, s)
+	return s
+}
+
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return ' '
+		}
+		return r
+	}, name)
+	name = strings.Trim(strings.TrimSpace(name), "'")
+	if name == "" {
+		name = "Лист"
+	}
+	return truncateRunes(name, 31)
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return strings.TrimSpace(string([]rune(s)[:n]))
+}
+
+// Name — итоговое имя листа (для ссылок из формул).
+func (s *Sheet) Name() string { return s.name }
+
+// SetWidth задаёт ширину колонки col (с 1) в символах.
+func (s *Sheet) SetWidth(col int, width float64) { s.widths[col] = width }
+
+// FreezeHeader закрепляет первую строку.
+func (s *Sheet) FreezeHeader() { s.freezeRow = 1 }
+
+// AddRow добавляет строку и возвращает её номер (с 1).
+func (s *Sheet) AddRow(cells ...Cell) int {
+	s.rows = append(s.rows, cells)
+	return len(s.rows)
+}
+
+// NextRow — номер строки, которую добавит следующий AddRow.
+func (s *Sheet) NextRow() int { return len(s.rows) + 1 }
+
+// ========================
+//         ЗАПИСЬ
+// ========================
+
+// Write пишет книгу в формате .xlsx.
+func (wb *Workbook) Write(w io.Writer) error {
+	z := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		body string
+	}{
+		{"[Content_Types].xml", wb.contentTypesXML()},
+		{"_rels/.rels", rootRelsXML},
+		{"xl/workbook.xml", wb.workbookXML()},
+		{"xl/_rels/workbook.xml.rels", wb.workbookRelsXML()},
+		{"xl/styles.xml", stylesXML},
+	}
+	for i, s := range wb.sheets {
+		files = append(files, struct {
+			name string
+			body string
+		}{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), s.xml()})
+	}
+
+	for _, f := range files {
+		fw, err := z.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.body); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+const rootRelsXML = xmlHeader +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// stylesXML — фиксированный набор стилей; порядок cellXfs совпадает с Style.
+const stylesXML = xmlHeader +
+	`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="3">` +
+	`<numFmt numFmtId="164" formatCode="#,##0.00"/>` +
+	`<numFmt numFmtId="165" formatCode="dd.mm.yyyy"/>` +
+	`<numFmt numFmtId="166" formatCode="0.00"/>` +
+	`</numFmts>` +
+	`<fonts count="2">` +
+	`<font><sz val="11"/><name val="Calibri"/></font>` +
+	`<font><b/><sz val="11"/><name val="Calibri"/></font>` +
+	`</fonts>` +
+	`<fills count="3">` +
+	`<fill><patternFill patternType="none"/></fill>` +
+	`<fill><patternFill patternType="gray125"/></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFE2E8F0"/><bgColor indexed="64"/></patternFill></fill>` +
+	`</fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="6">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="164" fontId="1" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/>` +
+	`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="166" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+func (wb *Workbook) contentTypesXML() string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range wb.sheets {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func (wb *Workbook) workbookXML() string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	b.WriteString(`<sheets>`)
+	for i, s := range wb.sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(s.name), i+1, i+1)
+	}
+	b.WriteString(`</sheets>`)
+	// формулы пересчитываются при открытии
+	b.WriteString(`<calcPr calcId="191029" fullCalcOnLoad="1"/>`)
+	b.WriteString(`</workbook>`)
+	return b.String()
+}
+
+func (wb *Workbook) workbookRelsXML() string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range wb.sheets {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	// стили идут после листов, чтобы не пересекаться с их rId
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(wb.sheets)+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+func (s *Sheet) xml() string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+
+	if s.freezeRow > 0 {
+		fmt.Fprintf(&b, `<sheetViews><sheetView workbookViewId="0"><pane ySplit="%d" topLeftCell="A%d" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`,
+			s.freezeRow, s.freezeRow+1)
+	}
+
+	if len(s.widths) > 0 {
+		b.WriteString(`<cols>`)
+		for col := 1; col <= maxCol(s.widths); col++ {
+			if w, ok := s.widths[col]; ok {
+				fmt.Fprintf(&b, `<col min="%d" max="%d" width="%g" customWidth="1"/>`, col, col, w)
+			}
+		}
+		b.WriteString(`</cols>`)
+	}
+
+	b.WriteString(`<sheetData>`)
+	for i, row := range s.rows {
+		r := i + 1
+		fmt.Fprintf(&b, `<row r="%d">`, r)
+		for j, c := range row {
+			writeCell(&b, Ref(j+1, r), c)
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData>`)
+
+	b.WriteString(`</worksheet>`)
+	return b.String()
+}
+
+func writeCell(b *strings.Builder, ref string, c Cell) {
+	style := ""
+	if c.style != StyleDefault {
+		style = fmt.Sprintf(` s="%d"`, c.style)
+	}
+
+	switch c.kind {
+	case kindEmpty:
+		if style != "" {
+			fmt.Fprintf(b, `<c r="%s"%s/>`, ref, style)
+		}
+	case kindText:
+		fmt.Fprintf(b, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(c.text))
+	case kindNumber:
+		fmt.Fprintf(b, `<c r="%s"%s><v>%s</v></c>`, ref, style, c.number)
+	case kindFormula:
+		fmt.Fprintf(b, `<c r="%s"%s><f>%s</f>`, ref, style, escape(c.formula))
+		if c.number != "" {
+			fmt.Fprintf(b, `<v>%s</v>`, c.number)
+		}
+		b.WriteString(`</c>`)
+	}
+}
+
+func maxCol(widths map[int]float64) int {
+	n := 0
+	for col := range widths {
+		n = max(n, col)
+	}
+	return n
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,161 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	date := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	wb := New()
+	s := wb.AddSheet("Сводка")
+	s.SetWidth(1, 30)
+	s.FreezeHeader()
+	s.AddRow(Header("ФИО"), Header("Дата"), Header("Сумма"))
+	s.AddRow(Text("Иванов <Иван> & \"сын\""), Date(date), Number("1234.56", StyleMoney))
+	s.AddRow(Text("  с пробелами  "), Empty(), Formula("SUM(C2:C2)", "1234.56", StyleMoneyBold))
+	s.AddRow()
+	s.AddRow(Empty(), Empty(), Int(-7))
+
+	// такое же имя получает суффикс, запрещённые символы заменяются
+	s2 := wb.AddSheet("сводка")
+	s2.AddRow(Text("второй"))
+	wb.AddSheet("a/b:c")
+
+	var buf bytes.Buffer
+	if err := wb.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	sheets, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []SheetData{
+		{Name: "Сводка", Rows: [][]string{
+			{"ФИО", "Дата", "Сумма"},
+			{"Иванов <Иван> & \"сын\"", "45352", "1234.56"},
+			{"  с пробелами  ", "", "1234.56"},
+			nil,
+			{"", "", "-7"},
+		}},
+		{Name: "сводка (2)", Rows: [][]string{{"второй"}}},
+		{Name: "a b c", Rows: nil},
+	}
+	if !reflect.DeepEqual(sheets, want) {
+		t.Errorf("Read() = %q\nwant %q", sheets, want)
+	}
+
+	if got := DateFromSerial(45352); !got.Equal(date) {
+		t.Errorf("DateFromSerial(45352) = %s, want %s", got, date)
+	}
+}
+
+func TestReadSharedStrings(t *testing.T) {
+	// так книгу сохраняют Excel и LibreOffice: sharedStrings, форматированный
+	// текст из нескольких фрагментов, путь к листу от корня архива
+	data := zipFiles(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Лист1" sheetId="1" r:id="rId3"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId3" Type="worksheet" Target="/xl/worksheets/data.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<si><t>Дата</t></si><si><r><t>Опер</t></r><r><t>ация</t></r></si></sst>`,
+		"xl/worksheets/data.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			`<row r="2"><c r="B2" t="s"><v>0</v></c><c r="D2" t="s"><v>1</v></c></row>` +
+			`<row><c t="str"><f>"a"&amp;"b"</f><v>ab</v></c><c><v>1.5</v></c></row>` +
+			`</sheetData></worksheet>`,
+	})
+
+	sheets, err := Read(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SheetData{{Name: "Лист1", Rows: [][]string{
+		nil,
+		{"", "Дата", "", "Операция"},
+		{"ab", "1.5"},
+	}}}
+	if !reflect.DeepEqual(sheets, want) {
+		t.Errorf("Read() = %q\nwant %q", sheets, want)
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "not a zip", data: []byte("date;kind;amount\n")},
+		{name: "zip without workbook", data: zipFiles(t, map[string]string{"a.txt": "x"})},
+		{name: "bad shared string index", data: zipFiles(t, map[string]string{
+			"xl/workbook.xml":            `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="A" r:id="r1"/></sheets></workbook>`,
+			"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="r1" Target="worksheets/a.xml"/></Relationships>`,
+			"xl/worksheets/a.xml":        `<worksheet><sheetData><row r="1"><c r="A1" t="s"><v>5</v></c></row></sheetData></worksheet>`,
+		})},
+		{name: "row out of range", data: zipFiles(t, map[string]string{
+			"xl/workbook.xml":            `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="A" r:id="r1"/></sheets></workbook>`,
+			"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="r1" Target="worksheets/a.xml"/></Relationships>`,
+			"xl/worksheets/a.xml":        `<worksheet><sheetData><row r="2000000"><c r="A1"><v>1</v></c></row></sheetData></worksheet>`,
+		})},
+	}
+	for _, tt := range tests {
+		if _, err := Read(bytes.NewReader(tt.data), int64(len(tt.data))); !errors.Is(err, ErrNotWorkbook) {
+			t.Errorf("%s: Read() = %v, want ErrNotWorkbook", tt.name, err)
+		}
+	}
+}
+
+func TestColumns(t *testing.T) {
+	tests := []struct {
+		col int
+		ref string
+	}{
+		{1, "A1"},
+		{26, "Z1"},
+		{27, "AA1"},
+		{52, "AZ1"},
+		{16384, "XFD1"},
+	}
+	for _, tt := range tests {
+		if got := Ref(tt.col, 1); got != tt.ref {
+			t.Errorf("Ref(%d, 1) = %q, want %q", tt.col, got, tt.ref)
+		}
+		if got, err := refColumn(tt.ref); err != nil || got != tt.col {
+			t.Errorf("refColumn(%q) = %d, %v; want %d", tt.ref, got, err, tt.col)
+		}
+	}
+
+	for _, ref := range []string{"", "1", "a1", "XFE1", strings.Repeat("Z", 10)} {
+		if _, err := refColumn(ref); err == nil {
+			t.Errorf("refColumn(%q): want error", ref)
+		}
+	}
+}
+
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}